@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local download cache",
+		Long: "Manage the local download cache.\n\n" +
+			"Downloads are checked against the sha256 digest GitHub publishes for the release asset when the release has one.\n" +
+			"The .sha256 file stored next to each archive is computed locally and only detects later corruption of the cache.",
+	}
+	cmd.AddCommand(newCacheListCmd(), newCachePruneCmd())
+
+	return cmd
+}
+
+func newCacheListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cached archives",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := utils.ListCache()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:mnd
+			fmt.Fprintln(w, "TOOL\tVERSION\tPLATFORM\tSIZE\tLAST USED")
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s/%s\t%d\t%s\n", entry.Tool, entry.Version, entry.OS, entry.Arch,
+					entry.Size, entry.ModTime.Format(time.DateTime))
+			}
+
+			return w.Flush() //nolint:wrapcheck
+		},
+	}
+}
+
+func newCachePruneCmd() *cobra.Command {
+	var (
+		all       bool
+		olderThan time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached archives that have not been used recently",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all {
+				olderThan = 0
+			}
+			return withInstallLock(cmd, func() error {
+				pruned, err := utils.PruneCache(olderThan)
+				for _, entry := range pruned {
+					log.Printf("Removed %s %s (%s/%s) from cache", entry.Tool, entry.Version, entry.OS, entry.Arch)
+				}
+
+				return err //nolint:wrapcheck
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "remove every cached archive")
+	cmd.Flags().DurationVar(&olderThan, "older-than", 30*24*time.Hour, "remove archives not used for this long") //nolint:mnd
+
+	return cmd
+}
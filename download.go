@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newDownloadCmd(platform *utils.Platform) *cobra.Command {
+	var (
+		toolName  string
+		version   string
+		targetOS  string
+		arch      string
+		outputDir string
+	)
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Download and extract a tool for another OS/arch into a directory without installing it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tool, err := utils.LookupTool(toolName)
+			if err != nil {
+				return err
+			}
+
+			goos, goarch, err := utils.ParseTarget(targetOS, arch)
+			if err != nil {
+				return err
+			}
+
+			version = strings.TrimPrefix(version, "v")
+			if version == "" {
+				if tool.LatestVersion == nil {
+					return fmt.Errorf("--version is required for %s", tool.Name)
+				}
+				log.Printf("Fetching latest %s version", tool.Name)
+				if version, err = tool.LatestVersion(platform); err != nil {
+					return fmt.Errorf("failed to get latest %s version: %w", tool.Name, err)
+				}
+			}
+
+			log.Printf("Downloading %s %s for %s/%s into %s", tool.Name, version, goos, goarch, outputDir)
+			if err := platform.DownloadTool(tool, version, goos, goarch, outputDir); err != nil {
+				return fmt.Errorf("failed to download %s %s: %w", tool.Name, version, err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&toolName, "tool", "protoc", "tool to download")
+	cmd.Flags().StringVar(&version, "version", "", "tool version, defaults to the latest release")
+	cmd.Flags().StringVar(&targetOS, "os", "linux", "target OS: linux, osx, win64")
+	cmd.Flags().StringVar(&arch, "arch", platform.Arch, "target architecture (GOARCH or release asset name)")
+	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "directory to extract the tool into")
+	_ = cmd.MarkFlagRequired("output")
+
+	return cmd
+}
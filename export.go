@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd(platform *utils.Platform) *cobra.Command {
+	var (
+		tools    []string
+		format   string
+		family   string
+		targetOS string
+		arch     string
+		output   string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print a Dockerfile RUN block or sh script that installs the resolved tool versions",
+		Long: "Resolve tool versions, release URLs and checksums and print a Dockerfile RUN block or a POSIX sh script\n" +
+			"that installs exactly the same artifacts into /usr/local.\n" +
+			"Tools are given as name or name=version, e.g. --tool protoc=29.3 --tool protoc-gen-go.\n\n" +
+			"The checksums are computed from the archives this command downloads. They are checked against the sha256\n" +
+			"digest of the GitHub release when the release publishes one; otherwise they pin what was downloaded here.\n" +
+			"The generated sh script installs into /usr/local and has to be run as root, e.g. sudo ./install-protoc.sh.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			goos, goarch, err := utils.ParseTarget(targetOS, arch)
+			if err != nil {
+				return err
+			}
+
+			artifacts := make([]utils.ResolvedArtifact, 0, len(tools))
+			for _, spec := range tools {
+				name, version, _ := strings.Cut(spec, "=")
+				tool, err := utils.LookupTool(name)
+				if err != nil {
+					return err
+				}
+
+				version = strings.TrimPrefix(version, "v")
+				if version == "" {
+					if tool.LatestVersion == nil {
+						return fmt.Errorf("a version is required for %s, pass --tool %s=<version>", tool.Name, tool.Name)
+					}
+					if version, err = tool.LatestVersion(platform); err != nil {
+						return fmt.Errorf("failed to get latest %s version: %w", tool.Name, err)
+					}
+				}
+
+				log.Printf("Resolving %s %s for %s/%s", tool.Name, version, goos, goarch)
+				artifact, err := platform.ResolveArtifact(tool, version, goos, goarch)
+				if err != nil {
+					return fmt.Errorf("failed to resolve %s %s: %w", tool.Name, version, err)
+				}
+				artifacts = append(artifacts, artifact)
+			}
+
+			script, err := utils.RenderInstallScript(artifacts, format, family)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = fmt.Fprint(cmd.OutOrStdout(), script)
+				return err //nolint:wrapcheck
+			}
+
+			mode := os.FileMode(0o644) //nolint:mnd
+			if format == utils.ExportFormatShell {
+				mode = 0o755 //nolint:mnd
+			}
+
+			return os.WriteFile(output, []byte(script), mode) //nolint:wrapcheck
+		},
+	}
+	cmd.Flags().StringSliceVar(&tools, "tool", []string{"protoc"}, "tool to include as name or name=version, repeatable")
+	cmd.Flags().StringVar(&format, "format", utils.ExportFormatDockerfile, "output format: dockerfile or sh")
+	cmd.Flags().StringVar(&family, "distro", utils.DistroFamilyDebian,
+		"base image distribution or family for the Dockerfile: debian, ubuntu, rhel, fedora, suse, alpine, arch")
+	cmd.Flags().StringVar(&targetOS, "os", "linux", "target OS: linux, osx")
+	cmd.Flags().StringVar(&arch, "arch", platform.Arch, "target architecture (GOARCH or release asset name)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write instead of stdout")
+
+	return cmd
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "history",
+		Short: "Show the install history",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := utils.ReadHistory()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:mnd
+			fmt.Fprintln(w, "TIME\tTOOL\tOLD\tNEW\tTARGET\tSOURCE")
+			for _, entry := range entries {
+				oldVersion := entry.OldVersion
+				if oldVersion == "" {
+					oldVersion = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Timestamp.Local().Format(time.DateTime),
+					entry.Tool, oldVersion, entry.NewVersion, entry.Target, entry.Source)
+			}
+
+			return w.Flush() //nolint:wrapcheck
+		},
+	}
+}
+
+func newRollbackCmd(platform *utils.Platform) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback",
+		Short: "Reinstall the protoc version that was active before the last install",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withSystemInstallLock(cmd, platform, func() error {
+				entry, err := platform.Rollback()
+				if err != nil {
+					return fmt.Errorf("failed to roll back: %w", err)
+				}
+				log.Printf("Rolled back %s from %s to %s", entry.Tool, entry.NewVersion, entry.OldVersion)
+
+				return nil
+			})
+		},
+	}
+}
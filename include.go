@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newIncludePathCmd(platform *utils.Platform) *cobra.Command {
+	var export bool
+	cmd := &cobra.Command{
+		Use:   "include-path",
+		Short: "Print the well-known types include directory of the protoc on PATH",
+		Long: "Print the include directory that ships with the protoc found first on PATH.\n" +
+			"Use it in build scripts: eval \"$(protocInstall include-path --export)\"",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := platform.ProtocIncludeDir()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			warnIncludeConflicts(platform, dir)
+
+			if export {
+				fmt.Fprintf(cmd.OutOrStdout(), "export PROTOC_INCLUDE='%s'\n", strings.ReplaceAll(dir, "'", `'\''`))
+				return nil
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), dir)
+
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&export, "export", false, "print a shell export of PROTOC_INCLUDE instead of the bare path")
+
+	return cmd
+}
+
+// warnIncludeConflicts предупреждает о копиях well-known types в стандартных каталогах include,
+// которые отличаются от поставляемых с установленным protoc.
+func warnIncludeConflicts(p *utils.Platform, includeDir string) {
+	conflicts, err := p.FindIncludeConflicts(includeDir)
+	if err != nil {
+		log.Printf("Failed to check well-known types for conflicts: %v", err)
+		return
+	}
+	for _, conflict := range conflicts {
+		log.Printf("Warning: %s has %d well-known .proto files that differ from %s (e.g. %s); "+
+			"pass -I %s before other include paths",
+			conflict.Dir, len(conflict.Files), includeDir, filepath.ToSlash(conflict.Files[0]), includeDir)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"text/tabwriter"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newInventoryCmd(platform *utils.Platform) *cobra.Command {
+	return &cobra.Command{
+		Use:   "inventory",
+		Short: "List every protoc and protoc-gen-* on PATH with its version and origin",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			binaries, err := platform.Inventory()
+			if err != nil {
+				return fmt.Errorf("failed to list binaries on PATH: %w", err)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0) //nolint:mnd
+			fmt.Fprintln(w, "NAME\tVERSION\tORIGIN\tPATH\tREAL PATH")
+			firstManaged := make(map[string]bool)
+			for _, binary := range binaries {
+				version := binary.Version
+				if version == "" {
+					version = "-"
+				}
+				path := binary.Path
+				if binary.Shadowed {
+					path += " (shadowed)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", binary.Name, version, binary.Origin, path, binary.RealPath)
+
+				if binary.Managed && !binary.Shadowed {
+					firstManaged[binary.Name] = true
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			warned := make(map[string]bool)
+			for _, binary := range binaries {
+				if binary.Managed && !firstManaged[binary.Name] && !warned[binary.Name] {
+					warned[binary.Name] = true
+					log.Printf("Warning: the managed %s in %s is shadowed by another %s earlier on PATH",
+						binary.Name, binary.Path, binary.Name)
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -1,139 +1,440 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"regexp"
-	"runtime"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
 )
 
+// exitInterrupted код завершения после SIGINT или SIGTERM, как у оболочки при прерывании по Ctrl-C.
+const exitInterrupted = 130
+
 func main() {
-	if err := devToolsInstall(); err != nil {
+	// SIGINT и SIGTERM отменяют контекст: запущенные команды завершаются, а временные каталоги
+	// удаляются отложенными вызовами по мере возврата ошибок. Повторный сигнал завершает процесс сразу.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Printf("Interrupted, cleaning up (press Ctrl-C again to exit immediately)")
+	}()
+
+	err := newRootCmd(utils.HostPlatform()).ExecuteContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Installation interrupted: %v", err)
+		os.Exit(exitInterrupted)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
-func devToolsInstall() error {
+
+func newRootCmd(platform *utils.Platform) *cobra.Command {
+	var opts installOptions
+	rootCmd := &cobra.Command{
+		Use:           "protocInstall",
+		Short:         "Installs and updates protoc to the latest stable version",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			timeout, err := cmd.Flags().GetDuration("command-timeout")
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			platform.SetContext(cmd.Context(), timeout)
+			utils.SetDefaultContext(cmd.Context(), timeout)
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.version = protocPin(opts.version)
+			opts.in, opts.out = cmd.InOrStdin(), cmd.ErrOrStderr()
+			return withSystemInstallLock(cmd, platform, func() error {
+				return devToolsInstall(platform, opts)
+			})
+		},
+	}
+	rootCmd.PersistentFlags().Duration("lock-timeout", 5*time.Minute, "how long to wait for another installer run to finish") //nolint:mnd
+	rootCmd.PersistentFlags().Duration("command-timeout", 30*time.Minute,                                                     //nolint:mnd
+		"how long a single external command (apt-get, brew, curl) may run before it is killed, 0 disables the limit")
+	rootCmd.PersistentFlags().StringVar(&platform.Escalation, "escalation", platform.Escalation,
+		fmt.Sprintf("how to gain root for system changes: %s (default auto, or $PROTOCINSTALL_ESCALATION)",
+			strings.Join(utils.EscalationModes, ", ")))
+	rootCmd.Flags().StringVar(&opts.from, "from", "", "install offline from a protoc release zip or a mirror directory")
+	rootCmd.Flags().StringVar(&opts.version, "version", "", "protoc version to install instead of the latest stable one")
+	rootCmd.Flags().BoolVar(&opts.noBrew, "no-brew", false, "on macOS install protoc from the GitHub release instead of Homebrew")
+	rootCmd.Flags().BoolVar(&opts.preferSystem, "prefer-system", false,
+		"on Linux install protoc with the distribution package manager when its version satisfies the requested one")
+	rootCmd.Flags().BoolVar(&opts.keepSystem, "keep-system", false,
+		"on Linux keep distribution protobuf packages and only install the managed protoc next to them")
+	rootCmd.Flags().BoolVarP(&opts.assumeYes, "yes", "y", false, "remove distribution protobuf packages without asking")
+	rootCmd.AddCommand(newInstallCmd(platform), newUseCmd(), newListCmd(), newCacheCmd(), newDownloadCmd(platform),
+		newHistoryCmd(), newRollbackCmd(platform), newVerifyCmd(platform),
+		newInventoryCmd(platform), newIncludePathCmd(platform), newExportCmd(platform))
+
+	return rootCmd
+}
+
+// protocPin приводит значение --version к номеру релиза protoc: v29.3 -> 29.3, 3.21.12 -> 21.12.
+// Так пин одинаково сравнивается с релизами GitHub, формулами Homebrew, пакетами дистрибутива и зеркалами.
+func protocPin(version string) string {
+	return utils.ProtocReleaseVersion(strings.TrimPrefix(version, "v"))
+}
+
+// withInstallLock выполняет fn под межпроцессной блокировкой установки,
+// чтобы параллельные запуски не распаковывали и не удаляли пакеты одновременно.
+func withInstallLock(cmd *cobra.Command, fn func() error) error {
+	timeout, err := cmd.Flags().GetDuration("lock-timeout")
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	lock, err := utils.AcquireInstallLockContext(cmd.Context(), timeout)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			log.Printf("Failed to release install lock: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// withSystemInstallLock выполняет fn под общей для всех пользователей блокировкой системной установки
+// и пользовательской блокировкой withInstallLock. Нужна командам, которые меняют /usr/local
+// или вызывают пакетный менеджер: запуски от разных пользователей и под sudo видят одну блокировку.
+func withSystemInstallLock(cmd *cobra.Command, platform *utils.Platform, fn func() error) error {
+	timeout, err := cmd.Flags().GetDuration("lock-timeout")
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	lock, err := platform.AcquireSystemInstallLock(cmd.Context(), timeout)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			log.Printf("Failed to release system install lock: %v", err)
+		}
+	}()
+
+	return withInstallLock(cmd, fn)
+}
+
+// installOptions задаёт источник и версию protoc для основной команды установки.
+type installOptions struct {
+	// from путь к локальному архиву релиза или каталогу-зеркалу для установки без сети.
+	from string
+	// version требуемая версия protoc, по умолчанию используется последняя стабильная.
+	version string
+	// noBrew устанавливает protoc на macOS из релиза GitHub, даже если Homebrew доступен.
+	noBrew bool
+	// preferSystem устанавливает protoc на Linux пакетным менеджером дистрибутива, если его версия подходит.
+	preferSystem bool
+	// keepSystem оставляет пакеты protobuf дистрибутива и только устанавливает управляемый protoc рядом с ними.
+	keepSystem bool
+	// assumeYes удаляет системные пакеты без вопроса.
+	assumeYes bool
+	// in и out используются для вопроса перед удалением системных пакетов.
+	in  io.Reader
+	out io.Writer
+}
+
+// confirmRemoval возвращает функцию, которая спрашивает пользователя, можно ли удалить системный пакет
+// вместе с зависящими от него пакетами.
+// Без терминала вопрос не задаётся и пакет остаётся, если не передан --yes.
+func confirmRemoval(opts installOptions) func(utils.SystemPackage) bool {
+	return func(pkg utils.SystemPackage) bool {
+		switch {
+		case pkg.Dependents == nil:
+			log.Printf("The system package %s will be removed, packages that depend on it may be removed too", pkg)
+		case len(pkg.Dependents) > 0:
+			log.Printf("The system package %s will be removed together with: %s", pkg, strings.Join(pkg.Dependents, ", "))
+		default:
+			log.Printf("The system package %s will be removed", pkg)
+		}
+		if opts.assumeYes {
+			return true
+		}
+		if !isInteractive(opts.in) {
+			log.Printf("Not removing %s without confirmation, pass --yes to remove it or --keep-system to keep it", pkg.Name)
+			return false
+		}
+
+		if len(pkg.Dependents) > 0 {
+			fmt.Fprintf(opts.out, "Remove %s and %d dependent packages (%s)? [y/N] ",
+				pkg, len(pkg.Dependents), strings.Join(pkg.Dependents, ", "))
+		} else {
+			fmt.Fprintf(opts.out, "Remove %s? [y/N] ", pkg)
+		}
+		answer, _ := bufio.NewReader(opts.in).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// isInteractive сообщает, можно ли задавать пользователю вопросы через in.
+func isInteractive(in io.Reader) bool {
+	if in == nil {
+		return false
+	}
+	file, ok := in.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := file.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func devToolsInstall(p *utils.Platform, opts installOptions) error {
 	log.Printf("Starting devTools installation")
-	platform := runtime.GOOS
-	log.Printf("Detected platform: %s", platform)
+	log.Printf("Detected platform: %s", p.OS)
 
-	switch platform {
+	switch p.OS {
 	case "darwin":
 		log.Printf("Processing installation for Darwin/MacOS")
-		var localProtocVersion string
-		var stableProtocVersion string
-		output, err := utils.RunCommandWithOutput("protoc", "--version")
+		useBrew := !opts.noBrew && opts.from == ""
+		if useBrew {
+			if _, err := p.Runner.Output("brew", "--version"); err != nil {
+				log.Printf("Homebrew not found, falling back to the GitHub release")
+				useBrew = false
+			}
+		}
+		if !useBrew {
+			if err := installProtoBufRelease(p, opts); err != nil {
+				return fmt.Errorf("failed to install protobuf on darwin: %w", err)
+			}
+			break
+		}
+
+		formula, err := p.FindBrewProtobufFormula(opts.version)
+		if errors.Is(err, utils.ErrNoBrewFormula) {
+			log.Printf("Warning: %v, falling back to the GitHub release", err)
+			if err := installProtoBufRelease(p, opts); err != nil {
+				return fmt.Errorf("failed to install protobuf on darwin: %w", err)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get stable protoc version: %w", err)
+		}
+		stableProtocVersion := formula.Version
+		log.Printf("Using Homebrew formula %s (%s)", formula.Name, stableProtocVersion)
+
+		output, err := p.Runner.Output("protoc", "--version")
 		if err != nil {
 			log.Printf("Protoc not found, attempting installation via brew")
-			if err = utils.RunCommand("brew", "install", "protobuf"); err != nil {
+			if err = p.BrewInstallOrUpgrade(formula); err != nil {
 				return fmt.Errorf("failed to install protobuf on darwin: %w", err)
 			}
 			log.Printf("Protobuf installed successfully, checking version")
-			output, err = utils.RunCommandWithOutput("protoc", "--version")
+			output, err = p.Runner.Output("protoc", "--version")
 			if err != nil {
 				return fmt.Errorf("failed to get protoc version after installation: %w", err)
 			}
 		}
-		// regex float number and get
-		localProtocVersion = regexp.MustCompile(`\d+\.\d+`).FindString(string(output))
+		localProtocVersion := utils.ParseProtocReleaseVersion(output)
 
 		log.Printf("Local protoc version: '%s'", (localProtocVersion))
-		stableProtocVersion, err = utils.GetStableProtocVersion()
-		if err != nil {
-			return fmt.Errorf("failed to get stable protoc version: %w", err)
-		}
 		log.Printf("Stable protoc version: '%s'", (stableProtocVersion))
 
-		if localProtocVersion != stableProtocVersion {
+		if utils.CompareVersions(localProtocVersion, utils.ProtocReleaseVersion(stableProtocVersion)) != 0 {
 			log.Printf("Version mismatch detected, updating protobuf")
-			if err = utils.RunCommand("brew", "install", "protobuf"); err != nil {
+			if err = p.BrewInstallOrUpgrade(formula); err != nil {
 				return fmt.Errorf("failed to install protobuf on darwin: %w", err)
 			}
 			log.Printf("Protobuf updated successfully, verifying installation")
-			_, err = utils.RunCommandWithOutput("protoc", "--version")
+			_, err = p.Runner.Output("protoc", "--version")
 			if err != nil {
 				return fmt.Errorf("failed to get protoc version after installation: %w", err)
 			}
 		}
 	case "linux":
 		log.Printf("Processing installation for Linux")
-		log.Printf("Initializing version variables")
-		var stableProtocVersion string
-		var localProtocVersion string
 
 		log.Printf("Detecting Linux distribution")
-		distro, _, err := utils.DetectLinuxDistribution()
+		distro, err := p.DetectLinuxDistribution()
 		if err != nil {
 			log.Printf("Failed to detect Linux distribution: %v", err)
 			return fmt.Errorf("failed to detect linux distribution: %w", err)
 		}
-		log.Printf("Detected Linux distribution: %s", distro)
+		log.Printf("Detected Linux distribution: %s (%s family)", distro, distro.Family)
+
+		musl := p.DetectLibc() == utils.LibcMusl
+		if musl {
+			log.Printf("Detected musl libc, the GitHub protoc release is linked against glibc")
+		}
 
-		log.Printf("Removing existing protobuf from package manager")
-		err = utils.RemovePackageManagerProtobuf(distro)
+		if (opts.preferSystem || musl) && opts.from == "" {
+			installed, err := installPackageManagerProtoc(p, distro, opts.version)
+			if err != nil {
+				return fmt.Errorf("failed to install protobuf with the package manager: %w", err)
+			}
+			if installed {
+				break
+			}
+			log.Printf("Falling back to the GitHub release")
+		}
+		if musl {
+			if err := p.EnsureGlibcCompat(distro); err != nil {
+				return fmt.Errorf("failed to prepare musl system for the protoc release: %w", err)
+			}
+		}
+
+		if opts.keepSystem {
+			log.Printf("Keeping system protobuf packages, the managed protoc has to come first on PATH")
+		} else {
+			log.Printf("Removing existing protobuf from package manager")
+			err = p.RemovePackageManagerProtobuf(distro, confirmRemoval(opts))
+			if errors.Is(err, utils.ErrRemovalDeclined) {
+				return fmt.Errorf("%w; rerun with --keep-system to install protoc next to it", err)
+			}
+			if err != nil {
+				log.Printf("Failed to remove existing protobuf: %v", err)
+				return fmt.Errorf("failed to remove package manager protobuf: %w", err)
+			}
+			log.Printf("Successfully removed existing protobuf installation")
+		}
+
+		if err := installProtoBufRelease(p, opts); err != nil {
+			return fmt.Errorf("failed to install protobuf on linux: %w", err)
+		}
+
+	default:
+		log.Printf("Unsupported platform detected: %s", p.OS)
+		return fmt.Errorf("unsupported platform: %s. Try to install protobuf manually", p.OS)
+	}
+	log.Printf("DevTools installation completed successfully")
+	return nil
+}
+
+// installPackageManagerProtoc устанавливает protoc пакетным менеджером, если его версия удовлетворяет pin,
+// а без pin — последней стабильной версии с GitHub. Возвращает false, если нужно устанавливать релиз GitHub.
+func installPackageManagerProtoc(p *utils.Platform, distro utils.LinuxDistribution, pin string) (bool, error) {
+	if pin == "" {
+		var err error
+		pin, err = p.GetGithubProtocVersion()
 		if err != nil {
-			log.Printf("Failed to remove existing protobuf: %v", err)
-			return fmt.Errorf("failed to remove package manager protobuf: %w", err)
+			return false, fmt.Errorf("failed to get stable protoc version: %w", err)
 		}
-		log.Printf("Successfully removed existing protobuf installation")
+	}
+
+	installed, err := p.InstallPackageManagerProtoc(distro, pin)
+	if err != nil || !installed {
+		return installed, err //nolint:wrapcheck
+	}
 
+	// Пакет мог не содержать protoc или поставить его не в PATH, тогда ставится релиз GitHub
+	output, err := p.Runner.Output("protoc", "--version")
+	if err != nil {
+		log.Printf("Distribution package did not provide a working protoc, falling back to the GitHub release: %v", err)
+		return false, nil
+	}
+	log.Printf("Using distribution protoc: %s", strings.TrimSpace(string(output)))
+	if other := utils.ShadowingBinary(p.Path("/usr/bin/protoc")); other != "" {
+		log.Printf("Warning: %s comes first on PATH and shadows the distribution protoc", other)
+	}
+
+	return true, nil
+}
+
+// installProtoBufRelease устанавливает protoc в /usr/local из релиза GitHub или локального архива,
+// если установленная версия отличается от требуемой.
+func installProtoBufRelease(p *utils.Platform, opts installOptions) error {
+	log.Printf("Initializing version variables")
+	var stableProtocVersion string
+	var localProtocVersion string
+
+	installProtoBuf := p.InstallProtoBufGithub
+	switch {
+	case opts.from != "":
+		log.Printf("Resolving protoc archive from %s", opts.from)
+		archive, version, err := p.ResolveLocalProtocArchive(opts.from, opts.version)
+		if err != nil {
+			return fmt.Errorf("failed to resolve local protoc archive: %w", err)
+		}
+		log.Printf("Using local archive %s for version %s", archive, version)
+		stableProtocVersion = version
+		installProtoBuf = func(version string) error {
+			return p.InstallProtoBufArchive(archive, version)
+		}
+	case opts.version != "":
+		stableProtocVersion = opts.version
+		log.Printf("Using requested version: %s", stableProtocVersion)
+	default:
 		log.Printf("Fetching stable protoc version")
-		stableProtocVersion, err = utils.GetStableProtocVersion()
+		var err error
+		stableProtocVersion, err = p.GetGithubProtocVersion()
 		if err != nil {
 			log.Printf("Failed to get stable protoc version: %v", err)
 			return fmt.Errorf("failed to get stable protoc version: %w", err)
 		}
 		log.Printf("Retrieved stable version: %s", stableProtocVersion)
+	}
 
-		// Check if protoc is installed
-		output, err := utils.RunCommandWithOutput("protoc", "--version")
+	// С --keep-system в PATH может оказаться protoc дистрибутива, поэтому проверяется управляемый бинарник
+	protoc := "protoc"
+	if opts.keepSystem {
+		protoc = p.Path("/usr/local/bin/protoc")
+	}
+
+	// Check if protoc is installed
+	output, err := p.Runner.Output(protoc, "--version")
+	if err != nil {
+		// If not installed, install it
+		log.Printf("Protoc not found, attempting installation")
+		if err = installProtoBuf(stableProtocVersion); err != nil {
+			return err
+		}
+		warnIfNotOnPath(p.Path("/usr/local/bin"))
+		warnIncludeConflicts(p, p.Path("/usr/local/include"))
+		log.Printf("Protobuf installed successfully, checking version")
+		output, err = p.Runner.Output(protoc, "--version")
 		if err != nil {
-			// If not installed, install it
-			log.Printf("Protoc not found, attempting installation")
-			if err = utils.InstallProtoBufLinuxGithub(stableProtocVersion); err != nil {
-				return fmt.Errorf("failed to install protobuf on linux: %w", err)
-			}
-			err = utils.RunCommand("export", "PATH=$PATH:/home/user/protoc/bin")
-			if err != nil {
-				return fmt.Errorf("failed to add protoc to path: %w", err)
-			}
-			log.Printf("Protobuf installed successfully, checking version")
-			_, err = utils.RunCommandWithOutput("protoc", "--version")
-			if err != nil {
-				return fmt.Errorf("failed to get protoc version after installation: %w", err)
-			}
+			return fmt.Errorf("failed to get protoc version after installation: %w", err)
 		}
+	}
 
-		localProtocVersion = regexp.MustCompile(`\d+\.\d+`).FindString(string(output))
-		if localProtocVersion == "" {
-			return fmt.Errorf("failed to parse local protoc version from: %s", output)
-		}
+	localProtocVersion = utils.ParseProtocReleaseVersion(output)
+	if localProtocVersion == "" {
+		return fmt.Errorf("failed to parse local protoc version from: %s", output)
+	}
 
-		log.Printf("Local protoc version: %s", localProtocVersion)
-		log.Printf("Stable protoc version: %s", stableProtocVersion)
+	log.Printf("Local protoc version: %s", localProtocVersion)
+	log.Printf("Stable protoc version: %s", stableProtocVersion)
 
-		if localProtocVersion != stableProtocVersion {
-			log.Printf("Version mismatch detected, updating protobuf")
-			if err = utils.InstallProtoBufLinuxGithub(stableProtocVersion); err != nil {
-				return fmt.Errorf("failed to update protobuf on linux: %w", err)
-			}
-			log.Printf("Protobuf updated successfully")
-			err = utils.RunCommand("export", "PATH=$PATH:/home/user/protoc/bin")
-			if err != nil {
-				return fmt.Errorf("failed to add protoc to path: %w", err)
-			}
-			err = utils.RunCommand("protoc", "--version")
-			if err != nil {
-				return fmt.Errorf("failed to get protoc version after installation: %w", err)
-			}
+	if utils.CompareVersions(localProtocVersion, utils.ProtocReleaseVersion(stableProtocVersion)) != 0 {
+		log.Printf("Version mismatch detected, updating protobuf")
+		if err = installProtoBuf(stableProtocVersion); err != nil {
+			return fmt.Errorf("failed to update protobuf: %w", err)
 		}
-
-	default:
-		log.Printf("Unsupported platform detected: %s", platform)
-		return fmt.Errorf("unsupported platform: %s. Try to install protobuf manually", platform)
+		log.Printf("Protobuf updated successfully")
+		warnIfNotOnPath(p.Path("/usr/local/bin"))
+		warnIncludeConflicts(p, p.Path("/usr/local/include"))
+		err = p.Runner.Run(protoc, "--version")
+		if err != nil {
+			return fmt.Errorf("failed to get protoc version after installation: %w", err)
+		}
+	} else if opts.keepSystem {
+		warnIfNotOnPath(p.Path("/usr/local/bin"))
 	}
-	log.Printf("DevTools installation completed successfully")
+
 	return nil
 }
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("not found")
+
+// fakeTransport отвечает на HTTP-запросы заранее заданными телами по URL.
+type fakeTransport map[string]string
+
+func (t fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := t[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     http.Header{},
+		Request:    req,
+	}, nil
+}
+
+func newTestPlatform(t *testing.T, goos string, runner *utils.FakeRunner, responses fakeTransport) *utils.Platform {
+	t.Helper()
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	return &utils.Platform{
+		OS:     goos,
+		Arch:   "amd64",
+		Root:   t.TempDir(),
+		Runner: runner,
+		HTTP:   &http.Client{Transport: responses},
+		// Временный корень доступен на запись, а проверки ожидают sudo как на реальной системе
+		Escalation: utils.EscalationSudo,
+	}
+}
+
+func writeOSRelease(t *testing.T, p *utils.Platform, content string) {
+	t.Helper()
+	path := p.Path("/etc/os-release")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// writeProtocTree имитирует распаковку архива protoc в каталог tree.
+func writeProtocTree(tree string) error {
+	if err := os.MkdirAll(filepath.Join(tree, "bin"), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(tree, "include", "google", "protobuf"), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tree, "include", "google", "protobuf", "descriptor.proto"), nil, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(tree, "bin", "protoc"), nil, 0o644)
+}
+
+// fakeProtoc отвечает как protoc 29.3 и записывает descriptor set, если его запросили.
+func fakeProtoc(args []string) ([]byte, error) {
+	for _, arg := range args {
+		if out, ok := strings.CutPrefix(arg, "--descriptor_set_out="); ok {
+			return nil, os.WriteFile(out, []byte("descriptor"), 0o644)
+		}
+	}
+	return []byte("libprotoc 29.3\n"), nil
+}
+
+const brewFormula = `{"name": "protobuf", "versions": {"stable": "29.3"}, "versioned_formulae": ["protobuf@21"]}`
+
+func TestDevToolsInstall_DarwinInstallsMissingProtoc(t *testing.T) {
+	installed := false
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch command {
+		case "protoc":
+			if !installed {
+				return nil, errNotFound
+			}
+			return []byte("libprotoc 29.3\n"), nil
+		case "brew":
+			switch args[0] {
+			case "list":
+				return nil, errNotFound
+			case "install":
+				installed = true
+			}
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://formulae.brew.sh/api/formula/protobuf.json": brewFormula,
+	})
+
+	require.NoError(t, devToolsInstall(p, installOptions{}))
+	assert.Equal(t, []string{
+		"brew --version",
+		"protoc --version",
+		"brew list --versions protobuf",
+		"brew install protobuf",
+		"protoc --version",
+	}, runner.Calls)
+}
+
+func TestDevToolsInstall_DarwinUpgradesOutdatedProtoc(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 28.2\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://formulae.brew.sh/api/formula/protobuf.json": brewFormula,
+	})
+
+	require.NoError(t, devToolsInstall(p, installOptions{}))
+	assert.Contains(t, runner.Calls, "brew upgrade protobuf", "Установленная формула должна обновляться через brew upgrade")
+	assert.NotContains(t, runner.Calls, "brew install protobuf")
+}
+
+func TestDevToolsInstall_DarwinPinnedVersionedFormula(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch command {
+		case "protoc":
+			return []byte("libprotoc 29.3\n"), nil
+		case "brew":
+			if args[0] == "list" {
+				return nil, errNotFound
+			}
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://formulae.brew.sh/api/formula/protobuf.json":    brewFormula,
+		"https://formulae.brew.sh/api/formula/protobuf@21.json": `{"name": "protobuf@21", "versions": {"stable": "21.12"}}`,
+	})
+
+	for _, pin := range []string{"21", "21.12", "3.21.12"} {
+		runner.Calls = nil
+		require.NoError(t, devToolsInstall(p, installOptions{version: protocPin(pin)}))
+		assert.Contains(t, runner.Calls, "brew install protobuf@21", "Пин %s должен подходить к формуле protobuf@21", pin)
+		assert.Contains(t, runner.Calls, "brew link --force --overwrite protobuf@21")
+	}
+}
+
+func TestDevToolsInstall_DarwinPinWithoutFormulaUsesGithub(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 3.19\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://formulae.brew.sh/api/formula/protobuf.json":    brewFormula,
+		"https://formulae.brew.sh/api/formula/protobuf@21.json": `{"name": "protobuf@21", "versions": {"stable": "21.12"}}`,
+	})
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "3.19"}))
+	for _, call := range runner.Calls {
+		assert.False(t, strings.HasPrefix(call, "brew install") || strings.HasPrefix(call, "brew upgrade"), call)
+	}
+}
+
+func TestDevToolsInstall_DarwinFallsBackToGithubWithoutBrew(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case command == "brew":
+			return nil, errNotFound
+		case command == "protoc":
+			return nil, errNotFound
+		case strings.HasSuffix(command, "/bin/protoc"):
+			return fakeProtoc(args)
+		case command == "curl":
+			return nil, os.WriteFile(args[2], []byte("zip"), 0o644)
+		case command == "unzip":
+			return nil, writeProtocTree(args[len(args)-1])
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/latest": `{"tag_name": "v29.3"}`,
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/tags/v29.3": `{"assets": [
+			{"name": "protoc-29.3-osx-aarch_64.zip"},
+			{"name": "protoc-29.3-osx-universal_binary.zip"}
+		]}`,
+	})
+
+	// protoc так и не появляется в PATH фейка, поэтому проверка после установки завершается ошибкой
+	err := devToolsInstall(p, installOptions{})
+	assert.ErrorContains(t, err, "failed to get protoc version after installation")
+	assert.Contains(t, runner.Calls,
+		"curl -fL -o "+filepath.Join(os.Getenv("PROTOCINSTALL_CACHE"), "protoc", "29.3", "darwin-universal", "protoc-29.3-osx-universal_binary.zip.part")+
+			" https://github.com/protocolbuffers/protobuf/releases/download/v29.3/protoc-29.3-osx-universal_binary.zip")
+	assert.NotContains(t, runner.Calls, "brew install protobuf")
+}
+
+func TestDevToolsInstall_DarwinNoBrewOption(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 29.3\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{})
+
+	require.NoError(t, devToolsInstall(p, installOptions{noBrew: true, version: "29.3"}))
+	assert.Equal(t, []string{"protoc --version"}, runner.Calls, "Homebrew не должен вызываться при --no-brew")
+}
+
+func TestDevToolsInstall_LinuxUpToDate(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch command {
+		case "dpkg-query":
+			return nil, errNotFound
+		case "protoc":
+			return []byte("libprotoc 29.3\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/latest": `{"tag_name": "v29.3"}`,
+	})
+	writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{}))
+	assert.Equal(t, []string{"dpkg-query -W -f=${Status} ${Version} protobuf-compiler", "protoc --version"}, runner.Calls)
+}
+
+func TestDevToolsInstall_LinuxPinnedUpToDate(t *testing.T) {
+	for _, version := range []string{"21.12", "3.21.12", "v21.12"} {
+		t.Run(version, func(t *testing.T) {
+			runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+				switch command {
+				case "dpkg-query":
+					return nil, errNotFound
+				case "protoc":
+					return []byte("libprotoc 3.21.12\n"), nil
+				}
+				return nil, nil
+			}}
+			p := newTestPlatform(t, "linux", runner, fakeTransport{})
+			writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+
+			require.NoError(t, devToolsInstall(p, installOptions{version: protocPin(version)}))
+			assert.Equal(t, []string{"dpkg-query -W -f=${Status} ${Version} protobuf-compiler", "protoc --version"}, runner.Calls,
+				"Установленная версия совпадает с пином, скачивание не нужно")
+		})
+	}
+}
+
+func TestDevToolsInstall_LinuxUpdatesFromGithub(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case command == "rpm":
+			return []byte("3.19.6"), nil
+		case command == "protoc":
+			return []byte("libprotoc 28.2\n"), nil
+		case strings.HasSuffix(command, "/bin/protoc"):
+			return fakeProtoc(args)
+		case command == "curl":
+			return nil, os.WriteFile(args[2], []byte("zip"), 0o644)
+		case command == "unzip":
+			return nil, writeProtocTree(args[len(args)-1])
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/latest": `{"tag_name": "v29.3"}`,
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/tags/v29.3": `{"assets": [
+			{"name": "protoc-29.3-linux-aarch_64.zip"},
+			{"name": "protoc-29.3-linux-x86_64.zip"}
+		]}`,
+	})
+	writeOSRelease(t, p, "ID=fedora\nVERSION_ID=41\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{assumeYes: true}))
+
+	assert.Contains(t, runner.Calls, "sudo dnf remove -y protobuf-compiler")
+	assert.Contains(t, runner.Calls,
+		"curl -fL -o "+filepath.Join(os.Getenv("PROTOCINSTALL_CACHE"), "protoc", "29.3", "linux-amd64", "protoc-29.3-linux-x86_64.zip.part")+
+			" https://github.com/protocolbuffers/protobuf/releases/download/v29.3/protoc-29.3-linux-x86_64.zip")
+	swapDir := p.Path(fmt.Sprintf("/usr/local/.protocinstall-%d", os.Getpid()))
+	assert.Contains(t, runner.Calls, "sudo mv "+swapDir+"/new/bin/protoc "+p.Path("/usr/local/bin/protoc"))
+	assert.Contains(t, runner.Calls, p.Path("/usr/local/bin/protoc")+" --version")
+}
+
+func TestDevToolsInstall_LinuxVerifiesReleaseChecksum(t *testing.T) {
+	for _, tt := range []struct {
+		digest string
+		err    error
+	}{
+		// sha256 содержимого "zip", которое скачивает фейковый curl
+		{digest: "sha256:4a70fe9aa6436e02c2dea340fbd1e352e4ef2d8ce6ca52ad25d4b95471fc8bf2"},
+		{digest: "sha256:0000000000000000000000000000000000000000000000000000000000000000", err: utils.ErrReleaseChecksumMismatch},
+	} {
+		t.Run(tt.digest[7:15], func(t *testing.T) {
+			runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+				switch {
+				case command == "dpkg-query":
+					return nil, errNotFound
+				case command == "protoc":
+					return []byte("libprotoc 28.2\n"), nil
+				case strings.HasSuffix(command, "/bin/protoc"):
+					return fakeProtoc(args)
+				case command == "curl":
+					return nil, os.WriteFile(args[2], []byte("zip"), 0o644)
+				case command == "unzip":
+					return nil, writeProtocTree(args[len(args)-1])
+				}
+				return nil, nil
+			}}
+			p := newTestPlatform(t, "linux", runner, fakeTransport{
+				"https://api.github.com/repos/protocolbuffers/protobuf/releases/tags/v29.3": `{"assets": [
+					{"name": "protoc-29.3-linux-x86_64.zip", "digest": "` + tt.digest + `"}
+				]}`,
+			})
+			writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+
+			err := devToolsInstall(p, installOptions{version: "29.3"})
+			cached := filepath.Join(os.Getenv("PROTOCINSTALL_CACHE"), "protoc", "29.3", "linux-amd64", "protoc-29.3-linux-x86_64.zip")
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				assert.NoFileExists(t, cached, "Архив с неверной суммой не должен попадать в кэш")
+				assert.NotContains(t, runner.Calls, "unzip -q -o "+cached+" -d", "Архив с неверной суммой не распаковывается")
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, cached)
+		})
+	}
+}
+
+func TestDevToolsInstall_LinuxMirrorLibraryVersionPin(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case command == "dpkg-query":
+			return nil, errNotFound
+		case command == "protoc":
+			return []byte("libprotoc 3.20.3\n"), nil
+		case strings.HasSuffix(command, "/bin/protoc"):
+			if _, err := fakeProtoc(args); err != nil {
+				return nil, err
+			}
+			return []byte("libprotoc 3.21.12\n"), nil
+		case command == "unzip":
+			return nil, writeProtocTree(args[len(args)-1])
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+	mirror := t.TempDir()
+	archive := filepath.Join(mirror, "releases", "download", "v21.12", "protoc-21.12-linux-x86_64.zip")
+	require.NoError(t, os.MkdirAll(filepath.Dir(archive), 0o755))
+	require.NoError(t, os.WriteFile(archive, []byte("zip"), 0o644))
+
+	require.NoError(t, devToolsInstall(p, installOptions{from: mirror, version: protocPin("3.21.12")}))
+	manifests, err := utils.ReadManifests()
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	assert.Equal(t, "21.12", manifests[0].Version, "Манифест хранит номер релиза, который сообщит verify")
+}
+
+func TestDevToolsInstall_LinuxPreferSystemPackage(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 3.21.12\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Candidates:  map[string]string{"protobuf-compiler": "3.21.12"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{preferSystem: true, version: "21"}))
+	assert.Equal(t, []string{"install protobuf-compiler"}, packages.Calls)
+	assert.Equal(t, []string{"protoc --version"}, runner.Calls, "Релиз GitHub не должен скачиваться")
+}
+
+func TestDevToolsInstall_LinuxPreferSystemExactPin(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 3.21.12\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Candidates:  map[string]string{"protobuf-compiler": "3.21.12"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=ubuntu\nVERSION_ID=\"24.04\"\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{preferSystem: true, version: protocPin("3.21.12")}))
+	assert.Equal(t, []string{"install protobuf-compiler"}, packages.Calls, "Пакет 3.21.12 совпадает с пином 3.21.12")
+	assert.Equal(t, []string{"protoc --version"}, runner.Calls, "Релиз GitHub не должен скачиваться")
+}
+
+func TestProtocPin(t *testing.T) {
+	for pin, expected := range map[string]string{"3.21.12": "21.12", "v21.12": "21.12", "v29.3": "29.3", "3.20.3": "3.20.3", "": ""} {
+		assert.Equal(t, expected, protocPin(pin), pin)
+	}
+}
+
+func TestDevToolsInstall_LinuxPreferSystemFallsBack(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 29.3\n"), nil
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Installed:   map[string]string{"protobuf-compiler": "3.12.4"},
+		Candidates:  map[string]string{"protobuf-compiler": "3.12.4"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=debian\nVERSION_ID=\"11\"\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{preferSystem: true, version: "29.3", assumeYes: true}))
+	assert.Equal(t, []string{"remove protobuf-compiler"}, packages.Calls, "Неподходящий пакет удаляется, как и без --prefer-system")
+}
+
+func TestDevToolsInstall_LinuxAsksBeforeRemoving(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		return []byte("libprotoc 29.3\n"), nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Installed:   map[string]string{"protobuf-compiler": "3.12.4"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=debian\nVERSION_ID=\"11\"\n")
+
+	var prompt strings.Builder
+	err := devToolsInstall(p, installOptions{version: "29.3", in: strings.NewReader("n\n"), out: &prompt})
+	require.ErrorIs(t, err, utils.ErrRemovalDeclined)
+	assert.Equal(t, "Remove protobuf-compiler 3.12.4 (apt-get)? [y/N] ", prompt.String())
+	assert.Empty(t, packages.Calls)
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "29.3", in: strings.NewReader("yes\n"), out: &prompt}))
+	assert.Equal(t, []string{"remove protobuf-compiler"}, packages.Calls)
+}
+
+func TestDevToolsInstall_LinuxPromptListsDependents(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		return []byte("libprotoc 29.3\n"), nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	p.PackageManager = &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Installed:   map[string]string{"protobuf-compiler": "3.12.4"},
+		Dependents:  map[string][]string{"protobuf-compiler": {"protobuf-compiler-grpc", "libprotoc-dev"}},
+	}
+	writeOSRelease(t, p, "ID=debian\nVERSION_ID=\"11\"\n")
+
+	var prompt strings.Builder
+	err := devToolsInstall(p, installOptions{version: "29.3", in: strings.NewReader("n\n"), out: &prompt})
+	require.ErrorIs(t, err, utils.ErrRemovalDeclined)
+	assert.Equal(t, "Remove protobuf-compiler 3.12.4 (apt-get) and 2 dependent packages (protobuf-compiler-grpc, libprotoc-dev)? [y/N] ",
+		prompt.String(), "Пользователь должен видеть всё, что удалит пакетный менеджер")
+}
+
+func TestDevToolsInstall_LinuxKeepSystem(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		if command == "protoc" {
+			return []byte("libprotoc 3.12.4\n"), nil
+		}
+		return []byte("libprotoc 29.3\n"), nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Installed:   map[string]string{"protobuf-compiler": "3.12.4"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=debian\nVERSION_ID=\"11\"\n")
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "29.3", keepSystem: true}))
+	assert.Empty(t, packages.Calls, "Системный пакет не трогается")
+	assert.Equal(t, []string{p.Path("/usr/local/bin/protoc") + " --version"}, runner.Calls,
+		"Версия проверяется у управляемого protoc, а не у первого в PATH")
+}
+
+// writeMuslLoader имитирует систему с musl, например Alpine.
+func writeMuslLoader(t *testing.T, p *utils.Platform) {
+	t.Helper()
+	path := p.Path("/lib/ld-musl-x86_64.so.1")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, nil, 0o755))
+}
+
+func TestDevToolsInstall_MuslUsesApkPackage(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		return []byte("libprotoc 29.3\n"), nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apk",
+		Package:     "protoc",
+		Candidates:  map[string]string{"protoc": "29.3"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=alpine\nVERSION_ID=3.21.0\n")
+	writeMuslLoader(t, p)
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "29.3"}))
+	assert.Equal(t, []string{"install protoc"}, packages.Calls, "На musl подходящий пакет apk ставится без --prefer-system")
+}
+
+func TestDevToolsInstall_MuslInstallsGcompat(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		return []byte("libprotoc 29.3\n"), nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apk",
+		Package:     "protoc",
+		Candidates:  map[string]string{"protoc": "24.4", "gcompat": "1.1.0"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=alpine\nVERSION_ID=3.19.0\n")
+	writeMuslLoader(t, p)
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "29.3"}))
+	assert.Equal(t, []string{"install gcompat"}, packages.Calls)
+}
+
+func TestDevToolsInstall_MuslPackageWithoutProtocFallsBack(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case command == "protoc":
+			return nil, errNotFound
+		case strings.HasSuffix(command, "/bin/protoc"):
+			return fakeProtoc(args)
+		case command == "curl":
+			return nil, os.WriteFile(args[2], []byte("zip"), 0o644)
+		case command == "unzip":
+			return nil, writeProtocTree(args[len(args)-1])
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+	packages := &utils.FakePackageManager{
+		ManagerName: "apk",
+		Package:     "protoc",
+		Candidates:  map[string]string{"protoc": "29.3", "gcompat": "1.1.0"},
+	}
+	p.PackageManager = packages
+	writeOSRelease(t, p, "ID=alpine\nVERSION_ID=3.21.0\n")
+	writeMuslLoader(t, p)
+
+	require.NoError(t, devToolsInstall(p, installOptions{version: "29.3", keepSystem: true}))
+	assert.Equal(t, []string{"install protoc", "install gcompat"}, packages.Calls,
+		"Если пакет не дал рабочий protoc, ставится gcompat и релиз GitHub")
+	assert.Contains(t, runner.Calls, p.Path("/usr/local/bin/protoc")+" --version")
+}
+
+func TestInstallProtocVersion_DarwinUsesGithubRelease(t *testing.T) {
+	runner := &utils.FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case strings.HasSuffix(command, "/bin/protoc"):
+			return fakeProtoc(args)
+		case command == "curl":
+			return nil, os.WriteFile(args[2], []byte("zip"), 0o644)
+		case command == "unzip":
+			return nil, writeProtocTree(args[len(args)-1])
+		}
+		return nil, nil
+	}}
+	p := newTestPlatform(t, "darwin", runner, fakeTransport{
+		"https://formulae.brew.sh/api/formula/protobuf.json":                    `{"name": "protobuf", "versions": {"stable": "30.0"}}`,
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/latest": `{"tag_name": "v29.3"}`,
+		"https://api.github.com/repos/protocolbuffers/protobuf/releases/tags/v29.3": `{"assets": [
+			{"name": "protoc-29.3-osx-universal_binary.zip"}
+		]}`,
+	})
+
+	require.NoError(t, installProtocVersion(p, nil, "", false))
+	active, err := utils.ActiveProtocVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "29.3", active, "Версия должна браться из релизов GitHub, а не из формулы Homebrew")
+}
+
+func TestInstallProtocVersion_RejectsInvalidVersion(t *testing.T) {
+	runner := &utils.FakeRunner{}
+	p := newTestPlatform(t, "linux", runner, fakeTransport{})
+
+	for _, version := range []string{"../../x", "29.3/../../x", "latest"} {
+		err := installProtocVersion(p, []string{version}, "", true)
+		require.ErrorIs(t, err, utils.ErrInvalidProtocVersion, version)
+	}
+	assert.Empty(t, runner.Calls, "Неверная версия должна отклоняться до скачивания")
+	assert.NoDirExists(t, filepath.Join(os.Getenv("PROTOCINSTALL_HOME"), "versions"))
+}
+
+func TestDevToolsInstall_UnsupportedPlatform(t *testing.T) {
+	runner := &utils.FakeRunner{}
+	p := newTestPlatform(t, "windows", runner, fakeTransport{})
+
+	err := devToolsInstall(p, installOptions{})
+	assert.ErrorContains(t, err, "unsupported platform: windows")
+	assert.Empty(t, runner.Calls)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// protocArchitectures сопоставляет GOARCH с суффиксами архитектуры в именах релизных архивов protoc.
+// В разных релизах одна и та же архитектура публиковалась под разными именами,
+// поэтому кандидаты перечислены в порядке предпочтения.
+var protocArchitectures = map[string][]string{
+	"amd64":   {"x86_64"},
+	"arm64":   {"aarch_64"},
+	"386":     {"x86_32"},
+	"ppc64le": {"ppcle_64", "ppc_64"},
+	"s390x":   {"s390_64"},
+}
+
+// protocAssetCandidates возвращает возможные имена релизного архива protoc для указанных ОС и архитектуры.
+// Для macOS в качестве запасного варианта используется универсальный бинарник.
+func protocAssetCandidates(version, goos, goarch string) ([]string, error) {
+	var platforms []string
+	switch goos {
+	case "linux":
+		for _, architecture := range protocArchitectures[goarch] {
+			platforms = append(platforms, "linux-"+architecture)
+		}
+	case "darwin":
+		switch goarch {
+		case "amd64":
+			platforms = []string{"osx-x86_64", "osx-universal_binary"}
+		case "arm64":
+			platforms = []string{"osx-aarch_64", "osx-universal_binary"}
+		case "universal":
+			platforms = []string{"osx-universal_binary"}
+		}
+	case "windows":
+		switch goarch {
+		case "amd64":
+			platforms = []string{"win64"}
+		case "386":
+			platforms = []string{"win32"}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", goos)
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("unsupported architecture: %s/%s", goos, goarch)
+	}
+
+	assets := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		assets = append(assets, fmt.Sprintf("protoc-%s-%s.zip", version, platform))
+	}
+
+	return assets, nil
+}
+
+// matchProtocAsset выбирает из списка архивов релиза архив protoc для указанных ОС и архитектуры.
+// Сначала проверяются известные имена, затем для Linux суффикс архитектуры сравнивается с GOARCH
+// без подчёркиваний, что позволяет подхватывать новые архитектуры (например, riscv_64) без изменения кода.
+func matchProtocAsset(version, goos, goarch string, assets []string) (string, bool) {
+	published := make(map[string]bool, len(assets))
+	for _, asset := range assets {
+		published[asset] = true
+	}
+
+	candidates, _ := protocAssetCandidates(version, goos, goarch)
+	for _, candidate := range candidates {
+		if published[candidate] {
+			return candidate, true
+		}
+	}
+	if goos != "linux" {
+		return "", false
+	}
+
+	prefix := fmt.Sprintf("protoc-%s-linux-", version)
+	for _, asset := range assets {
+		if !strings.HasPrefix(asset, prefix) || !strings.HasSuffix(asset, ".zip") {
+			continue
+		}
+		architecture := strings.TrimSuffix(strings.TrimPrefix(asset, prefix), ".zip")
+		if strings.ReplaceAll(architecture, "_", "") == goarch {
+			return asset, true
+		}
+	}
+
+	return "", false
+}
+
+// ResolveProtocAsset определяет имя релизного архива protoc для указанных ОС и архитектуры
+// по списку архивов релиза на GitHub. Если список получить не удалось, используется таблица известных имён.
+func (p *Platform) ResolveProtocAsset(version, goos, goarch string) (string, error) {
+	assets, err := p.fetchProtobufReleaseAssets(version)
+	if err != nil {
+		log.Printf("Failed to list release assets, falling back to known names: %v", err)
+		candidates, err := protocAssetCandidates(version, goos, goarch)
+		if err != nil {
+			return "", err
+		}
+		return candidates[0], nil
+	}
+
+	asset, ok := matchProtocAsset(version, goos, goarch, assets)
+	if !ok {
+		return "", fmt.Errorf("protobuf release v%s has no protoc asset for %s/%s", version, goos, goarch)
+	}
+
+	return asset, nil
+}
+
+// releaseAsset архив, опубликованный в релизе GitHub.
+type releaseAsset struct {
+	Name string `json:"name"`
+	// Digest контрольная сумма архива вида sha256:<hex>, которую считает GitHub. В старых релизах пустая.
+	Digest string `json:"digest"`
+}
+
+// fetchProtobufReleaseAssets возвращает имена архивов, опубликованных в релизе protobuf.
+func (p *Platform) fetchProtobufReleaseAssets(version string) ([]string, error) {
+	release, err := p.fetchReleaseAssets("protocolbuffers/protobuf", "v"+version)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]string, 0, len(release))
+	for _, asset := range release {
+		assets = append(assets, asset.Name)
+	}
+
+	return assets, nil
+}
+
+// fetchReleaseAssets возвращает архивы релиза tag репозитория repo.
+func (p *Platform) fetchReleaseAssets(repo, tag string) ([]releaseAsset, error) {
+	body, err := p.fetch(fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repo, url.PathEscape(tag)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s release %s: %w", repo, tag, err)
+	}
+
+	var release struct {
+		Assets []releaseAsset `json:"assets"`
+	}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return release.Assets, nil
+}
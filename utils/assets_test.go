@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchProtocAsset(t *testing.T) {
+	assets := []string{
+		"protobuf-29.3.tar.gz",
+		"protoc-29.3-linux-aarch_64.zip",
+		"protoc-29.3-linux-ppcle_64.zip",
+		"protoc-29.3-linux-riscv_64.zip",
+		"protoc-29.3-linux-s390_64.zip",
+		"protoc-29.3-linux-x86_32.zip",
+		"protoc-29.3-linux-x86_64.zip",
+		"protoc-29.3-osx-x86_64.zip",
+	}
+
+	tests := []struct {
+		goos     string
+		goarch   string
+		expected string
+		found    bool
+	}{
+		{"linux", "amd64", "protoc-29.3-linux-x86_64.zip", true},
+		{"linux", "arm64", "protoc-29.3-linux-aarch_64.zip", true},
+		{"linux", "386", "protoc-29.3-linux-x86_32.zip", true},
+		{"linux", "ppc64le", "protoc-29.3-linux-ppcle_64.zip", true},
+		{"linux", "s390x", "protoc-29.3-linux-s390_64.zip", true},
+		{"linux", "riscv64", "protoc-29.3-linux-riscv_64.zip", true},
+		{"linux", "mips64", "", false},
+		{"darwin", "amd64", "protoc-29.3-osx-x86_64.zip", true},
+		{"darwin", "arm64", "", false},
+		{"windows", "amd64", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			asset, found := matchProtocAsset("29.3", tt.goos, tt.goarch, assets)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, asset)
+		})
+	}
+}
+
+func TestMatchProtocAsset_AlternativeName(t *testing.T) {
+	asset, found := matchProtocAsset("3.20.0", "linux", "ppc64le", []string{"protoc-3.20.0-linux-ppc_64.zip"})
+	assert.True(t, found)
+	assert.Equal(t, "protoc-3.20.0-linux-ppc_64.zip", asset)
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrNoBrewFormula возвращается, если ни одна формула Homebrew не удовлетворяет требуемой версии protoc.
+var ErrNoBrewFormula = errors.New("no homebrew formula satisfies the requested protoc version")
+
+// BrewFormula описывает формулу Homebrew с protobuf и версию, которую она устанавливает.
+type BrewFormula struct {
+	Name    string
+	Version string
+}
+
+// Versioned сообщает, является ли формула версионированной (protobuf@21).
+// Такие формулы keg-only и требуют явного brew link.
+func (f BrewFormula) Versioned() bool {
+	return strings.Contains(f.Name, "@")
+}
+
+// fetchBrewFormula загружает описание формулы из API Homebrew.
+func (p *Platform) fetchBrewFormula(name string) (BrewProtocVersion, error) {
+	body, err := p.fetch(fmt.Sprintf("https://formulae.brew.sh/api/formula/%s.json", name))
+	if err != nil {
+		return BrewProtocVersion{}, fmt.Errorf("failed to fetch formula %s: %w", name, err)
+	}
+
+	var formula BrewProtocVersion
+	if err := json.Unmarshal(body, &formula); err != nil {
+		return BrewProtocVersion{}, fmt.Errorf("failed to unmarshal formula %s: %w", name, err)
+	}
+
+	return formula, nil
+}
+
+// FindBrewProtobufFormula выбирает формулу Homebrew, версия которой удовлетворяет pin.
+// Без pin возвращается основная формула protobuf. Иначе сначала проверяется protobuf,
+// затем версионированные формулы вида protobuf@<major>.
+func (p *Platform) FindBrewProtobufFormula(pin string) (BrewFormula, error) {
+	formula, err := p.fetchBrewFormula("protobuf")
+	if err != nil {
+		return BrewFormula{}, err
+	}
+
+	current := BrewFormula{Name: "protobuf", Version: formula.Version.Stable}
+	if pin == "" || VersionSatisfies(current.Version, pin) {
+		return current, nil
+	}
+
+	available := []string{current.Name + " " + current.Version}
+	for _, name := range formula.VersionedFormula {
+		versioned, err := p.fetchBrewFormula(name)
+		if err != nil {
+			log.Printf("Skipping formula %s: %v", name, err)
+			continue
+		}
+		if VersionSatisfies(versioned.Version.Stable, pin) {
+			return BrewFormula{Name: name, Version: versioned.Version.Stable}, nil
+		}
+		available = append(available, name+" "+versioned.Version.Stable)
+	}
+
+	return BrewFormula{}, fmt.Errorf("%w %s (available: %s)", ErrNoBrewFormula, pin, strings.Join(available, ", "))
+}
+
+// BrewInstallOrUpgrade устанавливает формулу, если её нет, или обновляет уже установленную.
+// Для версионированных формул protoc дополнительно линкуется в префикс Homebrew вместо основной формулы.
+func (p *Platform) BrewInstallOrUpgrade(formula BrewFormula) error {
+	action := "install"
+	var oldVersion string
+	if output, err := p.Runner.Output("brew", "list", "--versions", formula.Name); err == nil {
+		action = "upgrade"
+		oldVersion = parseProtocVersion(output)
+	}
+	if err := p.Runner.Run("brew", action, formula.Name); err != nil {
+		return fmt.Errorf("failed to %s %s: %w", action, formula.Name, err)
+	}
+	if err := AppendHistory(HistoryEntry{
+		Tool:       "protoc",
+		OldVersion: oldVersion,
+		NewVersion: formula.Version,
+		Source:     "homebrew:" + formula.Name,
+		Target:     HistoryTargetHomebrew,
+	}); err != nil {
+		log.Printf("Failed to record installation history: %v", err)
+	}
+
+	if !formula.Versioned() {
+		return nil
+	}
+	// Основная формула могла быть слинкована раньше, иначе brew link завершится конфликтом
+	if err := p.Runner.Run("brew", "unlink", "protobuf"); err != nil {
+		log.Printf("Failed to unlink protobuf: %v", err)
+	}
+	if err := p.Runner.Run("brew", "link", "--force", "--overwrite", formula.Name); err != nil {
+		return fmt.Errorf("failed to link %s: %w", formula.Name, err)
+	}
+
+	return nil
+}
+
+// VersionSatisfies сообщает, удовлетворяет ли версия version закреплённой версии pin.
+// pin сравнивается покомпонентно по своей длине: pin 21 удовлетворяет 21.12, а pin 21.5 — нет.
+func VersionSatisfies(version, pin string) bool {
+	versionParts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	pinParts := strings.Split(strings.TrimPrefix(pin, "v"), ".")
+	if len(pinParts) > len(versionParts) {
+		return CompareVersions(version, pin) == 0
+	}
+
+	return CompareVersions(strings.Join(versionParts[:len(pinParts)], "."), strings.Join(pinParts, ".")) == 0
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		version, pin string
+		expected     bool
+	}{
+		{"21.12", "21", true},
+		{"21.12", "21.12", true},
+		{"21.12", "21.5", false},
+		{"29.3", "29.3.0", true},
+		{"29.3", "3", false},
+		{"3.21.12", "v3.21", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, VersionSatisfies(tt.version, tt.pin), "%s satisfies %s", tt.version, tt.pin)
+	}
+}
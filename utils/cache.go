@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrCacheChecksumMismatch возвращается, если архив в кэше не совпадает с сохранённой контрольной суммой.
+var ErrCacheChecksumMismatch = errors.New("cached archive checksum mismatch")
+
+// CacheEntry описывает архив, сохранённый в локальном кэше загрузок.
+type CacheEntry struct {
+	Tool    string
+	Version string
+	OS      string
+	Arch    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// CacheDir возвращает каталог кэша загрузок.
+// По умолчанию это <UserCacheDir>/protocInstall, путь можно переопределить переменной PROTOCINSTALL_CACHE.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("PROTOCINSTALL_CACHE"); dir != "" {
+		return dir, nil
+	}
+
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %w", err)
+	}
+
+	return filepath.Join(userCache, "protocInstall"), nil
+}
+
+// FetchCachedArchive возвращает путь к архиву в кэше, при необходимости скачивая его функцией download.
+// Архивы хранятся по ключу tool/version/os-arch, рядом с архивом лежит файл .sha256.
+// Если контрольная сумма не совпадает, архив скачивается заново.
+func FetchCachedArchive(tool, version, goos, goarch, asset string, download func(dest string) error) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	entryDir := filepath.Join(dir, tool, version, goos+"-"+goarch)
+	archive := filepath.Join(entryDir, asset)
+
+	cached, err := LookupCachedArchive(tool, version, goos, goarch, asset)
+	switch {
+	case err == nil:
+		return cached, nil
+	case errors.Is(err, ErrCacheChecksumMismatch):
+		log.Printf("Cached %s is corrupted, downloading it again", archive)
+	case !errors.Is(err, os.ErrNotExist):
+		return "", err
+	}
+
+	if err := os.MkdirAll(entryDir, 0o755); err != nil { //nolint:mnd
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp := archive + ".part"
+	defer os.Remove(tmp)
+	if err := download(tmp); err != nil {
+		return "", err
+	}
+
+	sum, err := fileSHA256(tmp)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(archive+".sha256", []byte(sum+"  "+asset+"\n"), 0o644); err != nil { //nolint:mnd
+		return "", fmt.Errorf("failed to write checksum: %w", err)
+	}
+	if err := os.Rename(tmp, archive); err != nil {
+		return "", fmt.Errorf("failed to move archive into cache: %w", err)
+	}
+
+	return archive, nil
+}
+
+// CachedProtocArchive возвращает путь к архиву protoc указанной версии для платформы,
+// скачивая его только при отсутствии в кэше.
+func (p *Platform) CachedProtocArchive(version string) (string, error) {
+	goos, goarch := p.protocReleaseTarget()
+	return p.FetchToolArchive(Tools["protoc"], version, goos, goarch)
+}
+
+// protocReleaseTarget возвращает ОС и архитектуру релизного архива protoc для установки на платформу.
+// Для macOS используется универсальный бинарник, который работает и на Intel, и на Apple Silicon.
+func (p *Platform) protocReleaseTarget() (string, string) {
+	if p.OS == "darwin" {
+		return "darwin", "universal"
+	}
+
+	return p.OS, p.Arch
+}
+
+// LookupCachedArchive возвращает путь к архиву в кэше, если он есть и совпадает с сохранённой контрольной суммой.
+func LookupCachedArchive(tool, version, goos, goarch, asset string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	archive := filepath.Join(dir, tool, version, goos+"-"+goarch, asset)
+	if err := verifyCachedArchive(archive); err != nil {
+		return "", err
+	}
+	log.Printf("Using cached %s %s from %s", tool, version, archive)
+	now := time.Now()
+	_ = os.Chtimes(archive, now, now)
+
+	return archive, nil
+}
+
+// ListCache возвращает все архивы, сохранённые в кэше загрузок.
+func ListCache() ([]CacheEntry, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".sha256") || strings.HasSuffix(path, ".part") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		// Ожидаемая структура: tool/version/os-arch/asset
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 4 { //nolint:mnd
+			return nil
+		}
+		goos, goarch, _ := strings.Cut(parts[2], "-")
+
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		entries = append(entries, CacheEntry{
+			Tool:    parts[0],
+			Version: parts[1],
+			OS:      goos,
+			Arch:    goarch,
+			Path:    path,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// PruneCache удаляет из кэша архивы, которые не использовались дольше maxAge.
+// При maxAge равном нулю удаляются все архивы. Возвращает удалённые записи.
+func PruneCache(maxAge time.Duration) ([]CacheEntry, error) {
+	entries, err := ListCache()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []CacheEntry
+	for _, entry := range entries {
+		if maxAge > 0 && time.Since(entry.ModTime) < maxAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(entry.Path)); err != nil {
+			return pruned, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		pruned = append(pruned, entry)
+	}
+
+	return pruned, nil
+}
+
+func verifyCachedArchive(archive string) error {
+	expected, err := os.ReadFile(archive + ".sha256")
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	actual, err := fileSHA256(archive)
+	if err != nil {
+		return err
+	}
+	if fields := strings.Fields(string(expected)); len(fields) == 0 || fields[0] != actual {
+		return fmt.Errorf("%w: %s", ErrCacheChecksumMismatch, archive)
+	}
+
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
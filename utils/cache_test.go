@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCachedArchive(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+
+	downloads := 0
+	download := func(dest string) error {
+		downloads++
+		return os.WriteFile(dest, []byte("archive"), 0o644)
+	}
+
+	path, err := FetchCachedArchive("protoc", "29.3", "linux", "amd64", "protoc.zip", download)
+	require.NoError(t, err)
+	_, err = FetchCachedArchive("protoc", "29.3", "linux", "amd64", "protoc.zip", download)
+	require.NoError(t, err)
+	assert.Equal(t, 1, downloads, "Повторная установка должна брать архив из кэша")
+
+	// Порченый архив скачивается заново
+	require.NoError(t, os.WriteFile(path, []byte("corrupted"), 0o644))
+	_, err = FetchCachedArchive("protoc", "29.3", "linux", "amd64", "protoc.zip", download)
+	require.NoError(t, err)
+	assert.Equal(t, 2, downloads)
+
+	entries, err := ListCache()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, CacheEntry{
+		Tool: "protoc", Version: "29.3", OS: "linux", Arch: "amd64", Path: path,
+		Size: int64(len("archive")), ModTime: entries[0].ModTime,
+	}, entries[0])
+}
+
+func TestPruneCache(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+
+	download := func(dest string) error { return os.WriteFile(dest, []byte("archive"), 0o644) }
+	oldPath, err := FetchCachedArchive("protoc", "3.21", "linux", "amd64", "protoc.zip", download)
+	require.NoError(t, err)
+	_, err = FetchCachedArchive("protoc", "29.3", "linux", "amd64", "protoc.zip", download)
+	require.NoError(t, err)
+
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(oldPath, old, old))
+
+	pruned, err := PruneCache(24 * time.Hour)
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, "3.21", pruned[0].Version)
+
+	pruned, err = PruneCache(0)
+	require.NoError(t, err)
+	assert.Len(t, pruned, 1)
+
+	entries, err := ListCache()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -1,47 +1,39 @@
 package utils
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
-// RunCommand запускает команду и возвращает ошибку, если она возникает.
+// RunCommand запускает команду через DefaultRunner и возвращает ошибку, если она возникает.
+// Команда прерывается только по контексту, заданному SetDefaultContext.
 // Перед выполнением команда и её аргументы логируются.
 func RunCommand(command string, args ...string) error {
-	log.Printf("Running command: %s %s", command, strings.Join(args, " "))
-
-	cmd := exec.Command(command, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run command %s with args %v: %w", command, args, err)
-	}
-
-	return nil
+	return DefaultRunner.Run(command, args...) //nolint:wrapcheck
 }
 
-// RunCommandWithOutput запускает команду и возвращает ошибку, если она возникает, иначе возвращает стандартный вывод.
+// RunCommandWithOutput запускает команду через DefaultRunner и возвращает ошибку, если она возникает,
+// иначе возвращает стандартный вывод. Команда прерывается только по контексту, заданному SetDefaultContext.
 // Перед выполнением команда и её аргументы логируются.
 func RunCommandWithOutput(command string, args ...string) ([]byte, error) {
-	log.Printf("Running command: %s %s", command, strings.Join(args, " "))
-
-	cmd := exec.Command(command, args...)
-	cmd.Stderr = os.Stderr
+	return DefaultRunner.Output(command, args...) //nolint:wrapcheck
+}
 
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get command output: %w", err)
-	}
+// RunCommandContext запускает команду как RunCommand, но прерывает её вместе с группой процессов при отмене ctx.
+func RunCommandContext(ctx context.Context, command string, args ...string) error {
+	return ExecRunner{Context: ctx}.Run(command, args...)
+}
 
-	return output, nil
+// RunCommandWithOutputContext запускает команду как RunCommandWithOutput, но прерывает её вместе
+// с группой процессов при отмене ctx.
+func RunCommandWithOutputContext(ctx context.Context, command string, args ...string) ([]byte, error) {
+	return ExecRunner{Context: ctx}.Output(command, args...)
 }
 
 // CheckGitlabEnvVariables проверяет наличие необходимых переменных окружения.
@@ -66,15 +58,17 @@ func CheckGitlabEnvVariables(cmd *cobra.Command, args []string) error {
 
 // IsContainerRunning проверяет, запущен ли указанный контейнер.
 func IsContainerRunning(containerName string) (bool, error) {
-	cmd := exec.Command("docker", "ps", "--format", "{{.Names}}")
-	var out bytes.Buffer
-	cmd.Stdout = &out
+	return HostPlatform().IsContainerRunning(containerName)
+}
 
-	if err := cmd.Run(); err != nil {
+// IsContainerRunning проверяет, запущен ли указанный контейнер.
+func (p *Platform) IsContainerRunning(containerName string) (bool, error) {
+	output, err := quietRunner(p.Runner, nil, io.Discard).Output("docker", "ps", "--format", "{{.Names}}")
+	if err != nil {
 		return false, fmt.Errorf("ошибка при выполнении docker ps: %w", err)
 	}
 
-	containers := strings.Split(strings.TrimSpace(out.String()), "\n")
+	containers := strings.Split(strings.TrimSpace(string(output)), "\n")
 	for _, c := range containers {
 		if c == containerName {
 			return true, nil
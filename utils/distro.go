@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// Семейства дистрибутивов Linux. Дистрибутивы одного семейства используют один пакетный менеджер
+// и одинаковые имена пакетов.
+const (
+	DistroFamilyDebian = "debian"
+	DistroFamilyRHEL   = "rhel"
+	DistroFamilySUSE   = "suse"
+	DistroFamilyAlpine = "alpine"
+	DistroFamilyArch   = "arch"
+)
+
+// distroFamilies сопоставляет ID дистрибутива и его семейство.
+// Производные дистрибутивы (Linux Mint, Pop!_OS, Rocky, AlmaLinux, Amazon Linux, Manjaro и др.)
+// сюда не попадают: их семейство определяется по ID_LIKE.
+var distroFamilies = map[string]string{
+	"debian":   DistroFamilyDebian,
+	"ubuntu":   DistroFamilyDebian,
+	"rhel":     DistroFamilyRHEL,
+	"centos":   DistroFamilyRHEL,
+	"fedora":   DistroFamilyRHEL,
+	"suse":     DistroFamilySUSE,
+	"opensuse": DistroFamilySUSE,
+	"sles":     DistroFamilySUSE,
+	"alpine":   DistroFamilyAlpine,
+	"arch":     DistroFamilyArch,
+}
+
+// LinuxDistribution сведения о дистрибутиве из os-release.
+type LinuxDistribution struct {
+	// ID значение ID в нижнем регистре, например ubuntu или rocky.
+	ID string
+	// VersionID значение VERSION_ID, например 24.04. Может быть пустым у rolling-дистрибутивов.
+	VersionID string
+	// IDLike список родительских дистрибутивов из ID_LIKE в порядке близости.
+	IDLike []string
+	// PrettyName значение PRETTY_NAME для вывода пользователю.
+	PrettyName string
+	// Family семейство дистрибутива или пустая строка, если его не удалось определить.
+	Family string
+}
+
+// String возвращает название дистрибутива для вывода пользователю.
+func (d LinuxDistribution) String() string {
+	if d.PrettyName != "" {
+		return d.PrettyName
+	}
+
+	return strings.TrimSpace(d.ID + " " + d.VersionID)
+}
+
+// distroFamily определяет семейство по ID, а затем по ID_LIKE.
+func distroFamily(id string, idLike []string) string {
+	for _, candidate := range append([]string{id}, idLike...) {
+		if family, ok := distroFamilies[candidate]; ok {
+			return family
+		}
+		// opensuse-leap, opensuse-tumbleweed, sle-micro и т.п.
+		if strings.Contains(candidate, "suse") || strings.HasPrefix(candidate, "sle") {
+			return DistroFamilySUSE
+		}
+	}
+
+	return ""
+}
+
+// DetectLinuxDistribution возвращает ID и VERSION_ID дистрибутива текущей системы.
+// Для вариантов SUSE ID равен "suse".
+//
+// Deprecated: используйте Platform.DetectLinuxDistribution, который возвращает семейство дистрибутива.
+func DetectLinuxDistribution() (string, string, error) {
+	distro, err := HostPlatform().DetectLinuxDistribution()
+	if err != nil {
+		return "", "", err
+	}
+	if distro.Family == DistroFamilySUSE {
+		return "suse", distro.VersionID, nil
+	}
+
+	return distro.ID, distro.VersionID, nil
+}
+
+// DetectLinuxDistribution определяет дистрибутив Linux и его семейство по os-release в корне платформы.
+func (p *Platform) DetectLinuxDistribution() (LinuxDistribution, error) {
+	release, err := ReadOSRelease(p.Root)
+	if err != nil {
+		return LinuxDistribution{}, err
+	}
+
+	distro := distributionFromOSRelease(release)
+	if distro.ID == "" {
+		return LinuxDistribution{}, errors.New("could not determine Linux distribution")
+	}
+
+	return distro, nil
+}
+
+// IsAlpine проверяет по os-release в корне платформы, что это Alpine Linux или производный от него дистрибутив.
+func (p *Platform) IsAlpine() bool {
+	release, err := ReadOSRelease(p.Root)
+	if err != nil {
+		return false
+	}
+
+	return distributionFromOSRelease(release).Family == DistroFamilyAlpine
+}
+
+// distributionFromOSRelease заполняет LinuxDistribution из переменных os-release.
+func distributionFromOSRelease(release OSRelease) LinuxDistribution {
+	distro := LinuxDistribution{
+		ID:         strings.ToLower(release["ID"]),
+		VersionID:  release["VERSION_ID"],
+		PrettyName: release["PRETTY_NAME"],
+	}
+	if idLike, ok := release["ID_LIKE"]; ok {
+		distro.IDLike = strings.Fields(strings.ToLower(idLike))
+	}
+	distro.Family = distroFamily(distro.ID, distro.IDLike)
+
+	return distro
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLinuxDistribution(t *testing.T) {
+	tests := []struct {
+		name      string
+		osRelease string
+		expected  LinuxDistribution
+	}{
+		{
+			name:      "ubuntu",
+			osRelease: "PRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\nID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"24.04\"\n",
+			expected: LinuxDistribution{
+				ID: "ubuntu", VersionID: "24.04", IDLike: []string{"debian"},
+				PrettyName: "Ubuntu 24.04.1 LTS", Family: DistroFamilyDebian,
+			},
+		},
+		{
+			name:      "linux mint",
+			osRelease: "NAME=\"Linux Mint\"\nID=linuxmint\nID_LIKE=\"ubuntu debian\"\nVERSION_ID=\"22\"\n",
+			expected: LinuxDistribution{
+				ID: "linuxmint", VersionID: "22", IDLike: []string{"ubuntu", "debian"}, Family: DistroFamilyDebian,
+			},
+		},
+		{
+			name:      "rocky",
+			osRelease: "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.4\"\n",
+			expected: LinuxDistribution{
+				ID: "rocky", VersionID: "9.4", IDLike: []string{"rhel", "centos", "fedora"}, Family: DistroFamilyRHEL,
+			},
+		},
+		{
+			name:      "amazon linux",
+			osRelease: "ID=\"amzn\"\nID_LIKE=\"fedora\"\nVERSION_ID=\"2023\"\n",
+			expected: LinuxDistribution{
+				ID: "amzn", VersionID: "2023", IDLike: []string{"fedora"}, Family: DistroFamilyRHEL,
+			},
+		},
+		{
+			name:      "opensuse tumbleweed",
+			osRelease: "ID=\"opensuse-tumbleweed\"\nID_LIKE=\"opensuse suse\"\nVERSION_ID=\"20241018\"\n",
+			expected: LinuxDistribution{
+				ID: "opensuse-tumbleweed", VersionID: "20241018", IDLike: []string{"opensuse", "suse"}, Family: DistroFamilySUSE,
+			},
+		},
+		{
+			name:      "manjaro",
+			osRelease: "ID=manjaro\nID_LIKE=arch\n",
+			expected:  LinuxDistribution{ID: "manjaro", IDLike: []string{"arch"}, Family: DistroFamilyArch},
+		},
+		{
+			name:      "unknown",
+			osRelease: "ID=plan9\n",
+			expected:  LinuxDistribution{ID: "plan9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+			require.NoError(t, os.MkdirAll(p.Path("/etc"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(p.Path("/etc"), "os-release"), []byte(tt.osRelease), 0o644))
+
+			distro, err := p.DetectLinuxDistribution()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, distro)
+		})
+	}
+}
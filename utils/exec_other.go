@@ -0,0 +1,20 @@
+//go:build !unix
+
+package utils
+
+import "os/exec"
+
+// processGroup на платформах без групп процессов: отмена завершает только саму команду.
+type processGroup struct {
+	cmd *exec.Cmd
+}
+
+func startProcessGroup(cmd *exec.Cmd) *processGroup {
+	return &processGroup{cmd: cmd}
+}
+
+func (g *processGroup) terminate() error {
+	return g.cmd.Process.Kill() //nolint:wrapcheck
+}
+
+func (g *processGroup) release() {}
@@ -0,0 +1,102 @@
+//go:build unix
+
+package utils
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecRunnerTimeout(t *testing.T) {
+	runner := ExecRunner{Timeout: 200 * time.Millisecond}
+
+	start := time.Now()
+	_, err := runner.Output("sleep", "30")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "timed out after 200ms")
+	assert.Less(t, time.Since(start), 10*time.Second, "Команда должна завершаться по таймауту")
+
+	output, err := runner.Output("echo", "ok")
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", string(output))
+}
+
+func TestExecRunnerCancelKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	err := ExecRunner{Context: ctx}.Run("sh", "-c", "sleep 30 & echo $! > "+pidFile+"; wait")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "interrupted")
+
+	content, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return syscall.Kill(pid, 0) != nil
+	}, 5*time.Second, 50*time.Millisecond, "Дочерний процесс команды должен завершаться вместе с ней")
+}
+
+func TestSetDefaultContext(t *testing.T) {
+	defer func(runner CommandRunner) { DefaultRunner = runner }(DefaultRunner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	SetDefaultContext(ctx, time.Minute)
+
+	require.ErrorIs(t, RunCommand("true"), context.Canceled)
+	_, err := HostPlatform().Runner.Output("true")
+	require.ErrorIs(t, err, context.Canceled, "HostPlatform должна использовать DefaultRunner с контекстом")
+}
+
+func TestDetachedRunner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := ExecRunner{Context: ctx}
+	require.ErrorIs(t, runner.Run("true"), context.Canceled)
+	require.NoError(t, detachedRunner(runner).Run("true"), "Отмена не должна прерывать отвязанные команды")
+
+	fake := &FakeRunner{}
+	assert.Same(t, fake, detachedRunner(fake))
+}
+
+func TestExecRunnerForegroundTerminal(t *testing.T) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("нет управляющего терминала")
+	}
+	defer tty.Close()
+	if foreground, err := terminalProcessGroup(tty); err != nil || foreground != ownProcessGroup() {
+		t.Skip("тесты запущены не на переднем плане терминала")
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	require.NoError(t, ExecRunner{}.Run("true"))
+	foreground, err := terminalProcessGroup(tty)
+	require.NoError(t, err)
+	assert.Equal(t, ownProcessGroup(), foreground, "После команды терминал должен вернуться установщику")
+
+	// Ctrl-C получает только группа команды, установщик должен узнать о нём
+	require.Error(t, ExecRunner{}.Run("sh", "-c", "kill -INT $$"))
+	select {
+	case <-interrupts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Прерывание команды должно передаваться установщику")
+	}
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package utils
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalProcessGroup возвращает группу процессов переднего плана терминала (tcgetpgrp).
+func terminalProcessGroup(tty *os.File) (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCGPGRP,
+		uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+
+	return int(pgrp), nil
+}
+
+// setTerminalProcessGroup выводит группу pgrp на передний план терминала (tcsetpgrp).
+func setTerminalProcessGroup(tty *os.File, pgrp int) error {
+	value := int32(pgrp)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCSPGRP,
+		uintptr(unsafe.Pointer(&value))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// ownProcessGroup возвращает группу процессов установщика.
+func ownProcessGroup() int {
+	return syscall.Getpgrp()
+}
@@ -0,0 +1,22 @@
+//go:build unix && !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+// На остальных Unix группа команды не выводится на передний план терминала:
+// она по-прежнему завершается целиком, но sudo в ней не сможет запросить пароль.
+func terminalProcessGroup(*os.File) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func setTerminalProcessGroup(*os.File, int) error {
+	return errors.ErrUnsupported
+}
+
+func ownProcessGroup() int {
+	return -1
+}
@@ -0,0 +1,105 @@
+//go:build unix
+
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// processGroup группа процессов одной команды. Команда всегда запускается в собственной группе,
+// чтобы при отмене завершить вместе с ней и дочерние процессы, например dpkg, запущенный apt-get.
+type processGroup struct {
+	cmd *exec.Cmd
+	// tty управляющий терминал, если группа команды выведена на передний план.
+	tty *os.File
+
+	mu   sync.Mutex
+	kill *time.Timer
+	done bool
+}
+
+// startProcessGroup настраивает запуск cmd в отдельной группе процессов. Если установщик работает
+// на переднем плане терминала, группа команды выводится на передний план: так sudo может запросить пароль,
+// а Ctrl-C получают все процессы команды.
+func startProcessGroup(cmd *exec.Cmd) *processGroup {
+	group := &processGroup{cmd: cmd}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return group
+	}
+	if foreground, err := terminalProcessGroup(tty); err != nil || foreground != ownProcessGroup() {
+		tty.Close()
+		return group
+	}
+	group.tty = tty
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = int(tty.Fd())
+
+	return group
+}
+
+// terminate отправляет SIGTERM группе процессов команды, а через killGracePeriod — SIGKILL,
+// если к этому времени команда ещё не завершилась.
+func (g *processGroup) terminate() error {
+	pgid := g.cmd.Process.Pid
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return os.ErrProcessDone
+	}
+	g.kill = time.AfterFunc(killGracePeriod, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if !g.done {
+			_ = syscall.Kill(-pgid, syscall.SIGKILL)
+		}
+	})
+
+	return syscall.Kill(-pgid, syscall.SIGTERM) //nolint:wrapcheck
+}
+
+// release вызывается после завершения команды: отменяет отложенный SIGKILL, чтобы он не попал в группу
+// с тем же номером, возвращает терминал группе установщика и передаёт ей Ctrl-C, которым была прервана команда.
+func (g *processGroup) release() {
+	g.mu.Lock()
+	g.done = true
+	if g.kill != nil {
+		g.kill.Stop()
+	}
+	g.mu.Unlock()
+
+	if g.tty == nil {
+		return
+	}
+	defer g.tty.Close()
+	if g.cmd.Process == nil {
+		return
+	}
+
+	// Установщик сейчас в фоновой группе, и без игнорирования SIGTTOU смена группы его остановит
+	signal.Ignore(syscall.SIGTTOU)
+	_ = setTerminalProcessGroup(g.tty, ownProcessGroup())
+	signal.Reset(syscall.SIGTTOU)
+
+	// Ctrl-C получила только группа команды, поэтому прерывание передаётся установщику явно
+	if status, ok := exitStatus(g.cmd); ok && status.Signaled() && status.Signal() == syscall.SIGINT {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}
+}
+
+func exitStatus(cmd *exec.Cmd) (syscall.WaitStatus, bool) {
+	if cmd.ProcessState == nil {
+		return 0, false
+	}
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+
+	return status, ok
+}
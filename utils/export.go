@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Форматы, в которых можно выгрузить установку инструментов.
+const (
+	ExportFormatDockerfile = "dockerfile"
+	ExportFormatShell      = "sh"
+)
+
+// exportPrefix каталог, в который сгенерированный скрипт устанавливает инструменты.
+const exportPrefix = "/usr/local"
+
+// ResolvedArtifact релизный архив инструмента с точной версией, адресом и контрольной суммой.
+type ResolvedArtifact struct {
+	Tool    string
+	Version string
+	Asset   string
+	URL     string
+	SHA256  string
+}
+
+// ResolveArtifact находит архив инструмента для платформы и вычисляет его SHA-256.
+// Архив скачивается в кэш загрузок, если его там ещё нет. Сумма считается по нашей копии архива:
+// при скачивании она сверяется с суммой из релиза GitHub, только если релиз её публикует.
+func (p *Platform) ResolveArtifact(tool Tool, version, goos, goarch string) (ResolvedArtifact, error) {
+	archive, err := p.FetchToolArchive(tool, version, goos, goarch)
+	if err != nil {
+		return ResolvedArtifact{}, err
+	}
+	sum, err := fileSHA256(archive)
+	if err != nil {
+		return ResolvedArtifact{}, err
+	}
+
+	asset := filepath.Base(archive)
+
+	return ResolvedArtifact{
+		Tool:    tool.Name,
+		Version: version,
+		Asset:   asset,
+		URL:     tool.ReleaseURL(version, asset),
+		SHA256:  sum,
+	}, nil
+}
+
+// exportPrerequisites команды установки curl, unzip и tar для семейства дистрибутивов базового образа.
+var exportPrerequisites = map[string]string{
+	DistroFamilyDebian: "apt-get update && apt-get install -y --no-install-recommends ca-certificates curl unzip && rm -rf /var/lib/apt/lists/*",
+	DistroFamilyRHEL:   "dnf install -y unzip tar gzip && (command -v curl >/dev/null || dnf install -y curl) && dnf clean all",
+	DistroFamilySUSE:   "zypper --non-interactive install curl unzip tar gzip && zypper clean --all",
+	// Релизный protoc собран под glibc, на Alpine ему нужен gcompat
+	DistroFamilyAlpine: "apk add --no-cache curl unzip tar gcompat",
+	DistroFamilyArch:   "pacman -Sy --noconfirm --needed curl unzip tar && pacman -Scc --noconfirm",
+}
+
+// RenderInstallScript формирует установку artifacts в /usr/local в виде блока RUN для Dockerfile
+// или POSIX sh-скрипта, который нужно запускать от root. family задаёт семейство дистрибутива базового образа и нужен только для Dockerfile;
+// можно передать как семейство, так и ID дистрибутива, например ubuntu.
+func RenderInstallScript(artifacts []ResolvedArtifact, format, family string) (string, error) {
+	var summary []string
+	for _, artifact := range artifacts {
+		summary = append(summary, artifact.Tool+" "+artifact.Version)
+	}
+	header := "# Generated by protocInstall: " + strings.Join(summary, ", ")
+
+	switch format {
+	case ExportFormatDockerfile:
+		resolved := distroFamily(strings.ToLower(family), nil)
+		prerequisites, ok := exportPrerequisites[resolved]
+		if !ok {
+			return "", fmt.Errorf("unsupported base image distribution: %s", family)
+		}
+		steps := append([]string{prerequisites, `tmp="$(mktemp -d)"`}, installSteps(artifacts, "sha256sum -c -")...)
+		steps = append(steps, `rm -rf "$tmp"`)
+
+		return header + "\nRUN set -eux; \\\n    " + strings.Join(steps, "; \\\n    ") + "\n", nil
+
+	case ExportFormatShell:
+		lines := []string{
+			"#!/bin/sh",
+			header,
+			"# Installs into " + exportPrefix + " and has to be run as root, e.g. with sudo.",
+			"set -eu",
+			"",
+			"sha256_check() {",
+			"  if command -v sha256sum >/dev/null 2>&1; then sha256sum -c -; else shasum -a 256 -c -; fi",
+			"}",
+			"",
+			`tmp="$(mktemp -d)"`,
+			`trap 'rm -rf "$tmp"' EXIT`,
+			"",
+		}
+		lines = append(lines, installSteps(artifacts, "sha256_check")...)
+
+		return strings.Join(lines, "\n") + "\n", nil
+	}
+
+	return "", fmt.Errorf("unsupported export format %s, expected %s or %s", format, ExportFormatDockerfile, ExportFormatShell)
+}
+
+// installSteps возвращает команды shell, которые скачивают, проверяют и устанавливают архивы.
+func installSteps(artifacts []ResolvedArtifact, checksum string) []string {
+	// В минимальных образах и на macOS каталога может не быть
+	steps := []string{fmt.Sprintf("mkdir -p %s/bin", exportPrefix)}
+	for _, artifact := range artifacts {
+		archive := `"$tmp/` + artifact.Asset + `"`
+		dir := `"$tmp/` + artifact.Tool + `"`
+		steps = append(steps,
+			"curl -fsSL -o "+archive+" "+shellQuote(artifact.URL),
+			fmt.Sprintf(`echo "%s  $tmp/%s" | %s`, artifact.SHA256, artifact.Asset, checksum),
+			"mkdir -p "+dir,
+		)
+		if strings.HasSuffix(artifact.Asset, ".zip") {
+			steps = append(steps, "unzip -q -o "+archive+" -d "+dir)
+		} else {
+			steps = append(steps, "tar -xzf "+archive+" -C "+dir)
+		}
+
+		if artifact.Tool == "protoc" {
+			steps = append(steps,
+				fmt.Sprintf(`install -m 755 "$tmp/protoc/bin/protoc" %s/bin/protoc`, exportPrefix),
+				fmt.Sprintf("mkdir -p %s/include", exportPrefix),
+				fmt.Sprintf(`rm -rf %s/include/google/protobuf`, exportPrefix),
+				fmt.Sprintf(`cp -R "$tmp/protoc/include/google" %s/include/`, exportPrefix),
+			)
+			continue
+		}
+		steps = append(steps, fmt.Sprintf(`install -m 755 "$(find %s -type f -name %s | head -n 1)" %s/bin/%s`,
+			dir, artifact.Tool, exportPrefix, artifact.Tool))
+	}
+
+	return steps
+}
+
+// shellQuote заключает s в одинарные кавычки для shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportArtifacts = []ResolvedArtifact{
+	{
+		Tool:    "protoc",
+		Version: "29.3",
+		Asset:   "protoc-29.3-linux-x86_64.zip",
+		URL:     "https://github.com/protocolbuffers/protobuf/releases/download/v29.3/protoc-29.3-linux-x86_64.zip",
+		SHA256:  "aaaa",
+	},
+	{
+		Tool:    "protoc-gen-go",
+		Version: "1.36.5",
+		Asset:   "protoc-gen-go.v1.36.5.linux.amd64.tar.gz",
+		URL:     "https://github.com/protocolbuffers/protobuf-go/releases/download/v1.36.5/protoc-gen-go.v1.36.5.linux.amd64.tar.gz",
+		SHA256:  "bbbb",
+	},
+}
+
+func TestRenderInstallScript_Dockerfile(t *testing.T) {
+	script, err := RenderInstallScript(exportArtifacts, ExportFormatDockerfile, "Ubuntu")
+	require.NoError(t, err)
+
+	assert.Equal(t, `# Generated by protocInstall: protoc 29.3, protoc-gen-go 1.36.5
+RUN set -eux; \
+    apt-get update && apt-get install -y --no-install-recommends ca-certificates curl unzip && rm -rf /var/lib/apt/lists/*; \
+    tmp="$(mktemp -d)"; \
+    mkdir -p /usr/local/bin; \
+    curl -fsSL -o "$tmp/protoc-29.3-linux-x86_64.zip" 'https://github.com/protocolbuffers/protobuf/releases/download/v29.3/protoc-29.3-linux-x86_64.zip'; \
+    echo "aaaa  $tmp/protoc-29.3-linux-x86_64.zip" | sha256sum -c -; \
+    mkdir -p "$tmp/protoc"; \
+    unzip -q -o "$tmp/protoc-29.3-linux-x86_64.zip" -d "$tmp/protoc"; \
+    install -m 755 "$tmp/protoc/bin/protoc" /usr/local/bin/protoc; \
+    mkdir -p /usr/local/include; \
+    rm -rf /usr/local/include/google/protobuf; \
+    cp -R "$tmp/protoc/include/google" /usr/local/include/; \
+    curl -fsSL -o "$tmp/protoc-gen-go.v1.36.5.linux.amd64.tar.gz" 'https://github.com/protocolbuffers/protobuf-go/releases/download/v1.36.5/protoc-gen-go.v1.36.5.linux.amd64.tar.gz'; \
+    echo "bbbb  $tmp/protoc-gen-go.v1.36.5.linux.amd64.tar.gz" | sha256sum -c -; \
+    mkdir -p "$tmp/protoc-gen-go"; \
+    tar -xzf "$tmp/protoc-gen-go.v1.36.5.linux.amd64.tar.gz" -C "$tmp/protoc-gen-go"; \
+    install -m 755 "$(find "$tmp/protoc-gen-go" -type f -name protoc-gen-go | head -n 1)" /usr/local/bin/protoc-gen-go; \
+    rm -rf "$tmp"
+`, script)
+
+	script, err = RenderInstallScript(exportArtifacts[:1], ExportFormatDockerfile, "alpine")
+	require.NoError(t, err)
+	assert.Contains(t, script, "apk add --no-cache curl unzip tar gcompat")
+
+	_, err = RenderInstallScript(exportArtifacts, ExportFormatDockerfile, "plan9")
+	assert.ErrorContains(t, err, "unsupported base image distribution: plan9")
+}
+
+func TestRenderInstallScript_Shell(t *testing.T) {
+	script, err := RenderInstallScript(exportArtifacts[:1], ExportFormatShell, "")
+	require.NoError(t, err)
+
+	assert.Contains(t, script, "#!/bin/sh\n# Generated by protocInstall: protoc 29.3\n"+
+		"# Installs into /usr/local and has to be run as root, e.g. with sudo.\nset -eu\n")
+	assert.Contains(t, script, "\nmkdir -p /usr/local/bin\ncurl ", "Каталог bin создаётся до установки бинарников")
+	assert.Contains(t, script, `trap 'rm -rf "$tmp"' EXIT`)
+	assert.Contains(t, script, `echo "aaaa  $tmp/protoc-29.3-linux-x86_64.zip" | sha256_check`)
+	assert.NotContains(t, script, "apt-get")
+
+	_, err = RenderInstallScript(exportArtifacts, "yaml", "")
+	assert.ErrorContains(t, err, "unsupported export format yaml")
+}
+
+func TestResolveArtifact(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+
+	asset := "protoc-gen-go.v1.36.5.linux.amd64.tar.gz"
+	_, err := FetchCachedArchive("protoc-gen-go", "1.36.5", "linux", "amd64", asset, func(dest string) error {
+		return os.WriteFile(dest, []byte("archive"), 0o644)
+	})
+	require.NoError(t, err)
+
+	p := &Platform{OS: "linux", Arch: "amd64"}
+	artifact, err := p.ResolveArtifact(Tools["protoc-gen-go"], "1.36.5", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, ResolvedArtifact{
+		Tool:    "protoc-gen-go",
+		Version: "1.36.5",
+		Asset:   asset,
+		URL:     "https://github.com/protocolbuffers/protobuf-go/releases/download/v1.36.5/" + asset,
+		// sha256("archive")
+		SHA256: "0eb3e36bfb24dcd9bb1d1bece1531216b59539a8fde17ee80224af0653c92aa3",
+	}, artifact)
+	assert.FileExists(t, filepath.Join(os.Getenv("PROTOCINSTALL_CACHE"), "protoc-gen-go", "1.36.5", "linux-amd64", asset))
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnscriptedCommand возвращается FakeRunner в строгом режиме для команд без заданного ответа.
+var ErrUnscriptedCommand = errors.New("unscripted command")
+
+// FakeRunner CommandRunner для тестов. Записывает все запущенные команды и отвечает на них
+// по полной командной строке вида "command arg1 arg2". Порядок поиска ответа: Errors, Outputs, Handle.
+// Команды без ответа завершаются успешно с пустым выводом, а в режиме Strict — ошибкой ErrUnscriptedCommand.
+type FakeRunner struct {
+	// Outputs сопоставляет командные строки и их стандартный вывод.
+	Outputs map[string]string
+	// Errors сопоставляет командные строки и возвращаемые ими ошибки.
+	Errors map[string]error
+	// Handle если задана, отвечает на команды, которых нет в Outputs и Errors.
+	Handle func(command string, args []string) ([]byte, error)
+	// Strict запрещает команды без заданного ответа.
+	Strict bool
+
+	mu sync.Mutex
+	// Calls запущенные команды в порядке вызова.
+	Calls []string
+	// Env переменные окружения, переданные через RunEnv, по командной строке.
+	Env map[string][]string
+}
+
+// Run реализует CommandRunner.
+func (f *FakeRunner) Run(command string, args ...string) error {
+	_, err := f.Output(command, args...)
+	return err
+}
+
+// RunEnv реализует CommandRunner и дополнительно записывает env в Env.
+func (f *FakeRunner) RunEnv(env []string, command string, args ...string) error {
+	f.mu.Lock()
+	if f.Env == nil {
+		f.Env = make(map[string][]string)
+	}
+	f.Env[commandLine(command, args)] = env
+	f.mu.Unlock()
+
+	return f.Run(command, args...)
+}
+
+// Output реализует CommandRunner.
+func (f *FakeRunner) Output(command string, args ...string) ([]byte, error) {
+	line := commandLine(command, args)
+	f.mu.Lock()
+	f.Calls = append(f.Calls, line)
+	f.mu.Unlock()
+
+	if err, ok := f.Errors[line]; ok {
+		return nil, err
+	}
+	if output, ok := f.Outputs[line]; ok {
+		return []byte(output), nil
+	}
+	if f.Handle != nil {
+		return f.Handle(command, args)
+	}
+	if f.Strict {
+		return nil, fmt.Errorf("%w: %s", ErrUnscriptedCommand, line)
+	}
+
+	return nil, nil
+}
+
+func commandLine(command string, args []string) string {
+	return strings.TrimSpace(command + " " + strings.Join(args, " "))
+}
+
+// FakePackageManager PackageManager для тестов. Хранит установленные пакеты в памяти
+// и записывает вызовы Install и Remove.
+type FakePackageManager struct {
+	// ManagerName значение, возвращаемое Name.
+	ManagerName string
+	// Package значение, возвращаемое ProtocPackage.
+	Package string
+	// Installed сопоставляет установленные пакеты и их версии.
+	Installed map[string]string
+	// Candidates сопоставляет пакеты и версии, доступные для установки.
+	Candidates map[string]string
+	// Dependents сопоставляет пакеты и пакеты, которые Remove удалит вместе с ними.
+	Dependents map[string][]string
+	// Calls вызовы Install и Remove в формате "install <pkg>".
+	Calls []string
+	// Err если задана, возвращается из Install и Remove.
+	Err error
+}
+
+// Name реализует PackageManager.
+func (f *FakePackageManager) Name() string { return f.ManagerName }
+
+// ProtocPackage реализует PackageManager.
+func (f *FakePackageManager) ProtocPackage() string { return f.Package }
+
+// IsInstalled сообщает, есть ли пакет в Installed.
+func (f *FakePackageManager) IsInstalled(pkg string) (bool, error) {
+	_, ok := f.Installed[pkg]
+	return ok, nil
+}
+
+// InstalledVersion возвращает версию пакета из Installed.
+func (f *FakePackageManager) InstalledVersion(pkg string) (string, error) {
+	version, ok := f.Installed[pkg]
+	if !ok {
+		return "", fmt.Errorf("package %s is not installed", pkg)
+	}
+
+	return version, nil
+}
+
+// CandidateVersion возвращает версию пакета из Candidates.
+func (f *FakePackageManager) CandidateVersion(pkg string) (string, error) {
+	version, ok := f.Candidates[pkg]
+	if !ok {
+		return "", fmt.Errorf("package %s is not available", pkg)
+	}
+
+	return version, nil
+}
+
+// Install записывает вызов и добавляет пакет в Installed с версией из Candidates.
+func (f *FakePackageManager) Install(pkg string) error {
+	f.Calls = append(f.Calls, "install "+pkg)
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.Installed == nil {
+		f.Installed = make(map[string]string)
+	}
+	f.Installed[pkg] = f.Candidates[pkg]
+
+	return nil
+}
+
+// Remove записывает вызов и удаляет пакет из Installed.
+func (f *FakePackageManager) Remove(pkg string) error {
+	f.Calls = append(f.Calls, "remove "+pkg)
+	if f.Err != nil {
+		return f.Err
+	}
+	delete(f.Installed, pkg)
+
+	return nil
+}
+
+// DependentPackages возвращает пакеты из Dependents.
+func (f *FakePackageManager) DependentPackages(pkg string) ([]string, error) {
+	return append([]string{}, f.Dependents[pkg]...), nil
+}
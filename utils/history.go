@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Цели установки, которые записываются в историю.
+const (
+	// HistoryTargetSystem установка protoc в /usr/local.
+	HistoryTargetSystem = "system"
+	// HistoryTargetVersions переключение активной версии в каталоге параллельных установок.
+	HistoryTargetVersions = "versions"
+	// HistoryTargetHomebrew установка или обновление формулы Homebrew.
+	HistoryTargetHomebrew = "homebrew"
+	// HistoryTargetPackageManager установка пакета protoc пакетным менеджером дистрибутива.
+	HistoryTargetPackageManager = "package-manager"
+)
+
+// ErrNothingToRollback возвращается, если в истории нет установки, которую можно откатить.
+var ErrNothingToRollback = errors.New("nothing to roll back")
+
+var protocVersionRegexp = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+
+// HistoryEntry запись истории установок.
+type HistoryEntry struct {
+	Tool       string    `json:"tool"`
+	OldVersion string    `json:"old_version,omitempty"` //nolint:tagliatelle
+	NewVersion string    `json:"new_version"`           //nolint:tagliatelle
+	Source     string    `json:"source"`
+	Target     string    `json:"target"`
+	Timestamp  time.Time `json:"timestamp"`
+	Files      []string  `json:"files,omitempty"`
+}
+
+// HistoryPath возвращает путь к файлу истории установок.
+func HistoryPath() (string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, "history.jsonl"), nil
+}
+
+// AppendHistory дописывает запись в конец файла истории. Существующие записи никогда не изменяются.
+func AppendHistory(entry HistoryEntry) error {
+	path, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	return nil
+}
+
+// ReadHistory возвращает все записи истории установок в порядке их добавления.
+func ReadHistory() ([]HistoryEntry, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Rollback возвращает toolchain к версии, которая была до последней установки.
+// Системная установка восстанавливается из локального кэша загрузок без обращения к сети,
+// для параллельных установок переключается ссылка current.
+func (p *Platform) Rollback() (HistoryEntry, error) {
+	entries, err := ReadHistory()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	if len(entries) == 0 {
+		return HistoryEntry{}, fmt.Errorf("%w: history is empty", ErrNothingToRollback)
+	}
+
+	last := entries[len(entries)-1]
+	// Старые записи хранят версию из вывода protoc --version (3.21.12), а кэш и каталоги версий — номер релиза (21.12)
+	last.OldVersion = ProtocReleaseVersion(last.OldVersion)
+	if last.OldVersion == "" {
+		return HistoryEntry{}, fmt.Errorf("%w: %s %s was a fresh install", ErrNothingToRollback, last.Tool, last.NewVersion)
+	}
+
+	switch last.Target {
+	case HistoryTargetVersions:
+		if err := useProtocVersion(last.OldVersion, "rollback"); err != nil {
+			return HistoryEntry{}, err
+		}
+	case HistoryTargetSystem:
+		goos, goarch := p.protocReleaseTarget()
+		archive, ok := lookupCachedToolArchive(Tools["protoc"], last.OldVersion, goos, goarch)
+		if !ok {
+			return HistoryEntry{}, fmt.Errorf("protoc %s is not in the local cache, reinstall it with --version %s",
+				last.OldVersion, last.OldVersion)
+		}
+		if err := p.installProtoBuf(last.OldVersion, "rollback", func(staging string) (string, error) {
+			return p.stageProtocTree(archive, last.OldVersion, staging)
+		}); err != nil {
+			return HistoryEntry{}, err
+		}
+	default:
+		return HistoryEntry{}, fmt.Errorf("rollback of %s installs is not supported, pin the version with --version %s",
+			last.Target, last.OldVersion)
+	}
+
+	return last, nil
+}
+
+// parseProtocVersion извлекает версию из вывода protoc --version.
+func parseProtocVersion(output []byte) string {
+	return protocVersionRegexp.FindString(string(output))
+}
+
+// ParseProtocReleaseVersion извлекает версию из вывода protoc --version и приводит её к нумерации релизов:
+// "libprotoc 3.21.12" превращается в "21.12". Результат можно сравнивать с версией релиза через CompareVersions.
+func ParseProtocReleaseVersion(output []byte) string {
+	return ProtocReleaseVersion(parseProtocVersion(output))
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollback_Versions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PROTOCINSTALL_HOME", home)
+	for _, version := range []string{"3.21", "29.3"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(home, "versions", version), 0o755))
+	}
+
+	p := &Platform{OS: "linux", Arch: "amd64"}
+	_, err := p.Rollback()
+	assert.ErrorIs(t, err, ErrNothingToRollback)
+
+	require.NoError(t, UseProtocVersion("3.21"))
+	require.NoError(t, UseProtocVersion("29.3"))
+
+	entry, err := p.Rollback()
+	require.NoError(t, err)
+	assert.Equal(t, "3.21", entry.OldVersion)
+
+	active, err := ActiveProtocVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "3.21", active)
+
+	history, err := ReadHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, HistoryEntry{
+		Tool:       "protoc",
+		OldVersion: "29.3",
+		NewVersion: "3.21",
+		Source:     "rollback",
+		Target:     HistoryTargetVersions,
+		Timestamp:  history[2].Timestamp,
+		Files:      []string{filepath.Join(home, "current")},
+	}, history[2])
+}
+
+func TestRollback_SystemRequiresCachedArchive(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+
+	require.NoError(t, AppendHistory(HistoryEntry{
+		Tool: "protoc", OldVersion: "28.2", NewVersion: "29.3", Source: "github", Target: HistoryTargetSystem,
+	}))
+
+	p := &Platform{OS: "linux", Arch: "amd64"}
+	_, err := p.Rollback()
+	assert.ErrorContains(t, err, "protoc 28.2 is not in the local cache")
+}
+
+func TestRollback_SystemFromCache(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+	t.Setenv("PROTOCINSTALL_CACHE", t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+
+	assets, err := Tools["protoc"].Assets("21.12", "linux", "amd64")
+	require.NoError(t, err)
+	_, err = FetchCachedArchive("protoc", "21.12", "linux", "amd64", assets[0], func(dest string) error {
+		return os.WriteFile(dest, []byte("zip"), 0o644)
+	})
+	require.NoError(t, err)
+
+	// protoc --version печатает 3.21.12, а релиз и ключ кэша — 21.12
+	require.NoError(t, AppendHistory(HistoryEntry{
+		Tool: "protoc", OldVersion: "3.21.12", NewVersion: "29.3", Source: "github", Target: HistoryTargetSystem,
+	}))
+
+	runner := &FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+		switch {
+		case command == "unzip":
+			tree := args[len(args)-1]
+			require.NoError(t, os.MkdirAll(filepath.Join(tree, "bin"), 0o755))
+			require.NoError(t, os.MkdirAll(filepath.Join(tree, "include", wellKnownTypesDir), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(tree, "include", wellKnownTypesDir, "descriptor.proto"), nil, 0o644))
+			return nil, os.WriteFile(filepath.Join(tree, "bin", "protoc"), nil, 0o644)
+		case filepath.Base(command) == "protoc":
+			for _, arg := range args {
+				if out, ok := strings.CutPrefix(arg, "--descriptor_set_out="); ok {
+					return nil, os.WriteFile(out, []byte("descriptor"), 0o644)
+				}
+			}
+			return []byte("libprotoc 29.3\n"), nil
+		}
+		return nil, nil
+	}}
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir(), Runner: runner, Escalation: EscalationNone}
+
+	entry, err := p.Rollback()
+	require.NoError(t, err)
+	assert.Equal(t, "21.12", entry.OldVersion)
+
+	history, err := ReadHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "29.3", history[1].OldVersion)
+	assert.Equal(t, "21.12", history[1].NewVersion)
+	assert.Equal(t, "rollback", history[1].Source)
+	assert.Equal(t, HistoryTargetSystem, history[1].Target)
+}
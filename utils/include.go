@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrIncludeDirNotFound возвращается, если рядом с protoc нет каталога include с well-known types.
+var ErrIncludeDirNotFound = errors.New("protoc include directory not found")
+
+// wellKnownTypesDir каталог well-known types внутри каталога include.
+var wellKnownTypesDir = filepath.Join("google", "protobuf")
+
+// standardIncludeDirs перечисляет каталоги, в которых компиляторы и системы сборки
+// обычно ищут google/protobuf/*.proto.
+var standardIncludeDirs = []string{
+	"/usr/include",
+	"/usr/local/include",
+	"/opt/homebrew/include",
+	"/opt/local/include",
+}
+
+// IncludeConflict копия well-known types, отличающаяся от файлов, поставляемых с используемым protoc.
+type IncludeConflict struct {
+	// Dir каталог include, в котором найдена копия.
+	Dir string
+	// Files well-known .proto, содержимое которых отличается.
+	Files []string
+}
+
+// ProtocIncludeDir возвращает каталог include, поставляемый вместе с первым protoc в PATH.
+// Этот путь следует передавать в protoc через -I или переменную PROTOC_INCLUDE.
+func (p *Platform) ProtocIncludeDir() (string, error) {
+	hits := LookPathAll("protoc")
+	if len(hits) == 0 {
+		return "", fmt.Errorf("%w: protoc is not on PATH", ErrIncludeDirNotFound)
+	}
+
+	dir := filepath.Join(filepath.Dir(filepath.Dir(realPath(hits[0]))), "include")
+	if !fileExists(filepath.Join(dir, wellKnownTypesDir, "descriptor.proto")) {
+		return "", fmt.Errorf("%w: no %s next to %s", ErrIncludeDirNotFound, filepath.Join(dir, wellKnownTypesDir), hits[0])
+	}
+
+	return dir, nil
+}
+
+// FindIncludeConflicts сравнивает well-known types из includeDir с копиями в стандартных каталогах include
+// и возвращает каталоги, в которых есть файлы с другим содержимым.
+func (p *Platform) FindIncludeConflicts(includeDir string) ([]IncludeConflict, error) {
+	reference, err := filepath.Glob(filepath.Join(includeDir, wellKnownTypesDir, "*.proto"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list well-known types: %w", err)
+	}
+	sort.Strings(reference)
+
+	var conflicts []IncludeConflict
+	for _, dir := range standardIncludeDirs {
+		dir = p.Path(dir)
+		if realPath(dir) == realPath(includeDir) {
+			continue
+		}
+
+		conflict := IncludeConflict{Dir: dir}
+		for _, file := range reference {
+			name := filepath.Base(file)
+			other := filepath.Join(dir, wellKnownTypesDir, name)
+			otherSum, err := fileSHA256(other)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			sum, err := fileSHA256(file)
+			if err != nil {
+				return nil, err
+			}
+			if sum != otherSum {
+				conflict.Files = append(conflict.Files, filepath.Join(wellKnownTypesDir, name))
+			}
+		}
+		if len(conflict.Files) > 0 {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWellKnownTypes(t *testing.T, includeDir string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(includeDir, "google", "protobuf")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestProtocIncludeDir(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+
+	t.Setenv("PATH", t.TempDir())
+	_, err := p.ProtocIncludeDir()
+	require.ErrorIs(t, err, ErrIncludeDirNotFound)
+
+	prefix := t.TempDir()
+	writeExecutable(t, filepath.Join(prefix, "bin", "protoc"))
+	t.Setenv("PATH", filepath.Join(prefix, "bin"))
+	_, err = p.ProtocIncludeDir()
+	require.ErrorIs(t, err, ErrIncludeDirNotFound)
+
+	writeWellKnownTypes(t, filepath.Join(prefix, "include"), map[string]string{"descriptor.proto": "v29"})
+	dir, err := p.ProtocIncludeDir()
+	require.NoError(t, err)
+	assert.Equal(t, realPath(filepath.Join(prefix, "include")), dir)
+}
+
+func TestFindIncludeConflicts(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+
+	managed := p.Path("/usr/local/include")
+	writeWellKnownTypes(t, managed, map[string]string{"descriptor.proto": "v29", "any.proto": "any"})
+	writeWellKnownTypes(t, p.Path("/usr/include"), map[string]string{
+		"descriptor.proto": "v3.12",
+		"any.proto":        "any",
+		"compiler.proto":   "only in the distro copy",
+	})
+	writeWellKnownTypes(t, p.Path("/opt/homebrew/include"), map[string]string{"descriptor.proto": "v29"})
+
+	conflicts, err := p.FindIncludeConflicts(managed)
+	require.NoError(t, err)
+	assert.Equal(t, []IncludeConflict{{
+		Dir:   p.Path("/usr/include"),
+		Files: []string{filepath.Join("google", "protobuf", "descriptor.proto")},
+	}}, conflicts)
+}
@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrProtocVersionNotInstalled возвращается, если запрошенная версия protoc не установлена в каталог версий.
+var ErrProtocVersionNotInstalled = errors.New("protoc version is not installed")
+
+// ErrInvalidProtocVersion возвращается, если версия не похожа на номер релиза protoc, например 29.3 или 30.0-rc1.
+var ErrInvalidProtocVersion = errors.New("invalid protoc version")
+
+// protocReleaseVersionRegexp номер релиза protoc. Версия становится именем каталога в versions,
+// поэтому всё, кроме цифр, точек и суффикса -rc, отклоняется.
+var protocReleaseVersionRegexp = regexp.MustCompile(`^\d+(?:\.\d+)*(?:-rc-?\d+)?$`)
+
+func validateProtocVersion(version string) error {
+	if !protocReleaseVersionRegexp.MatchString(version) {
+		return fmt.Errorf("%w: %q", ErrInvalidProtocVersion, version)
+	}
+
+	return nil
+}
+
+// ProtocInstallHome возвращает корневой каталог параллельных установок protoc.
+// По умолчанию это ~/.protocinstall, путь можно переопределить переменной окружения PROTOCINSTALL_HOME.
+func ProtocInstallHome() (string, error) {
+	if home := os.Getenv("PROTOCINSTALL_HOME"); home != "" {
+		return home, nil
+	}
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	return filepath.Join(userHome, ".protocinstall"), nil
+}
+
+// ProtocCurrentBinDir возвращает каталог bin активной версии protoc, который нужно добавить в PATH.
+func ProtocCurrentBinDir() (string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, "current", "bin"), nil
+}
+
+// InstallProtocVersion устанавливает protoc указанной версии в отдельный каталог versions/<version>.
+// Архив распаковывается во временный каталог, проверяется запуском protoc --version
+// и только после этого переименовывается в итоговый каталог.
+func (p *Platform) InstallProtocVersion(version string) (string, error) {
+	return p.installProtocVersion(version, func(staging string) (string, error) {
+		return p.stageProtocArchive(version, staging)
+	})
+}
+
+// InstallProtocVersionFromArchive устанавливает protoc из локального архива в каталог versions/<version>
+// без обращения к сети.
+func (p *Platform) InstallProtocVersionFromArchive(archive, version string) (string, error) {
+	return p.installProtocVersion(version, func(staging string) (string, error) {
+		return p.stageProtocTree(archive, version, staging)
+	})
+}
+
+func (p *Platform) installProtocVersion(version string, stage func(staging string) (string, error)) (string, error) {
+	if err := validateProtocVersion(version); err != nil {
+		return "", err
+	}
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	versionsDir := filepath.Join(home, "versions")
+	versionDir := filepath.Join(versionsDir, version)
+	if _, err := os.Stat(versionDir); err == nil {
+		log.Printf("Protoc %s is already installed in %s", version, versionDir)
+		return versionDir, nil
+	}
+
+	if err := os.MkdirAll(versionsDir, 0o755); err != nil { //nolint:mnd
+		return "", fmt.Errorf("failed to create versions directory: %w", err)
+	}
+
+	staging, err := os.MkdirTemp(versionsDir, ".staging-"+version+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	tree, err := stage(staging)
+	if err != nil {
+		return "", err
+	}
+	manifest, err := buildManifest(HistoryTargetVersions, version, tree, versionDir, []string{"bin", "include"})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tree, versionDir); err != nil {
+		return "", fmt.Errorf("failed to move protoc %s into place: %w", version, err)
+	}
+	if err := WriteManifest(manifest); err != nil {
+		log.Printf("Failed to record install manifest: %v", err)
+	}
+	log.Printf("Protoc %s installed into %s", version, versionDir)
+
+	return versionDir, nil
+}
+
+// stageProtocArchive берёт архив protoc из кэша загрузок, распаковывает его в каталог staging
+// и проверяет, что распакованный protoc запускается. Возвращает путь к распакованному дереву.
+func (p *Platform) stageProtocArchive(version, staging string) (string, error) {
+	archive, err := p.CachedProtocArchive(version)
+	if err != nil {
+		return "", err
+	}
+
+	return p.stageProtocTree(archive, version, staging)
+}
+
+// stageProtocTree распаковывает локальный архив protoc в каталог staging и проверяет распакованный protoc.
+func (p *Platform) stageProtocTree(archive, version, staging string) (string, error) {
+	tree := filepath.Join(staging, "tree")
+	if err := p.Runner.Run("unzip", "-q", "-o", archive, "-d", tree); err != nil {
+		return "", fmt.Errorf("failed to unzip protoc: %w", err)
+	}
+
+	protoc := filepath.Join(tree, "bin", "protoc")
+	if err := os.Chmod(protoc, 0o755); err != nil { //nolint:mnd
+		return "", fmt.Errorf("failed to chmod protoc: %w", err)
+	}
+	if _, err := p.Runner.Output(protoc, "--version"); err != nil {
+		// На musl загрузчик glibc отсутствует, и запуск завершается невнятным "not found"
+		if p.DetectLibc() == LibcMusl {
+			return "", fmt.Errorf("failed to run staged protoc %s: %w: %w; install %s or the distribution package",
+				version, ErrGlibcRequired, err, glibcCompatPackage)
+		}
+		return "", fmt.Errorf("failed to verify staged protoc %s: %w", version, err)
+	}
+	// Well-known types устанавливаются вместе с protoc, без них не собираются файлы с import "google/protobuf/..."
+	if !fileExists(filepath.Join(tree, "include", wellKnownTypesDir, "descriptor.proto")) {
+		return "", fmt.Errorf("protoc %s archive has no well-known types in include/%s", version, wellKnownTypesDir)
+	}
+	if err := p.SmokeTestProtoc(protoc, filepath.Join(tree, "include")); err != nil {
+		return "", fmt.Errorf("staged protoc %s is not usable: %w", version, err)
+	}
+
+	return tree, nil
+}
+
+// UseProtocVersion переключает ссылку current на установленную версию protoc.
+// Ссылка заменяется атомарно, поэтому параллельно запущенный protoc всегда видит целостный каталог.
+// Переключение записывается в историю установок.
+func UseProtocVersion(version string) error {
+	return useProtocVersion(version, "use")
+}
+
+func useProtocVersion(version, source string) error {
+	if err := validateProtocVersion(version); err != nil {
+		return err
+	}
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join("versions", version)
+	if _, err := os.Stat(filepath.Join(home, target)); err != nil {
+		return fmt.Errorf("%w: %s", ErrProtocVersionNotInstalled, version)
+	}
+
+	previous, err := ActiveProtocVersion()
+	if err != nil {
+		return err
+	}
+
+	current := filepath.Join(home, "current")
+	tmpLink := current + ".tmp"
+	_ = os.Remove(tmpLink)
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return fmt.Errorf("failed to create symlink for protoc %s: %w", version, err)
+	}
+	if err := os.Rename(tmpLink, current); err != nil {
+		_ = os.Remove(tmpLink)
+		return fmt.Errorf("failed to switch active protoc to %s: %w", version, err)
+	}
+
+	if previous == version {
+		return nil
+	}
+
+	return AppendHistory(HistoryEntry{
+		Tool:       "protoc",
+		OldVersion: previous,
+		NewVersion: version,
+		Source:     source,
+		Target:     HistoryTargetVersions,
+		Files:      []string{current},
+	})
+}
+
+// ActiveProtocVersion возвращает версию protoc, на которую указывает ссылка current.
+// Если активная версия не выбрана, возвращается пустая строка.
+func ActiveProtocVersion() (string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	target, err := os.Readlink(filepath.Join(home, "current"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read active protoc link: %w", err)
+	}
+
+	return filepath.Base(target), nil
+}
+
+// ListInstalledProtocVersions возвращает установленные версии protoc, отсортированные по возрастанию.
+func ListInstalledProtocVersions() ([]string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(home, "versions"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read versions directory: %w", err)
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Пропускаем незавершённые установки
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		versions = append(versions, entry.Name())
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+
+	return versions, nil
+}
+
+// CompareVersions сравнивает две версии вида major.minor[.patch][-rcN] покомпонентно.
+// Возвращает -1, 0 или 1. Release candidate младше релиза с тем же номером: 30.0-rc1 < 30.0.
+// Нечисловые компоненты сравниваются как строки.
+func CompareVersions(a, b string) int {
+	aCore, aPre, aHasPre := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, bHasPre := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	if c := compareVersionParts(strings.Split(aCore, "."), strings.Split(bCore, ".")); c != 0 {
+		return c
+	}
+
+	switch {
+	case aHasPre && !bHasPre:
+		return -1
+	case !aHasPre && bHasPre:
+		return 1
+	}
+	// rc1, rc-1 и rc10 сравниваются по номеру
+	trim := func(pre string) string { return strings.TrimLeft(strings.TrimPrefix(pre, "rc"), "-") }
+
+	return compareVersionParts([]string{trim(aPre)}, []string{trim(bPre)})
+}
+
+// compareVersionParts сравнивает компоненты версий: числа по значению, остальное как строки.
+// Недостающие компоненты считаются нулями.
+func compareVersionParts(aParts, bParts []string) int {
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart string
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+		if aPart == "" {
+			aNum, aErr = 0, nil
+		}
+		if bPart == "" {
+			bNum, bErr = 0, nil
+		}
+		if aErr != nil || bErr != nil {
+			if c := strings.Compare(aPart, bPart); c != 0 {
+				return c
+			}
+			continue
+		}
+		if aNum != bNum {
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.21", "29.3", -1},
+		{"29.3", "29.3", 0},
+		{"29.3", "29.3.0", 0},
+		{"v29.3", "29.2", 1},
+		{"3.21.12", "3.21.9", 1},
+		{"30.0-rc1", "30.0", -1},
+		{"30.0", "30.0-rc1", 1},
+		{"30.0-rc2", "30.0-rc10", -1},
+		{"30.0-rc-1", "30.0-rc1", 0},
+		{"30.0-rc1", "29.3", 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestUseProtocVersion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PROTOCINSTALL_HOME", home)
+
+	for _, version := range []string{"29.3", "3.21", ".staging-30.0-123"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(home, "versions", version, "bin"), 0o755))
+	}
+
+	versions, err := ListInstalledProtocVersions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3.21", "29.3"}, versions, "Незавершённые установки не должны попадать в список")
+
+	active, err := ActiveProtocVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "", active)
+
+	require.NoError(t, UseProtocVersion("3.21"))
+	require.NoError(t, UseProtocVersion("29.3"))
+	active, err = ActiveProtocVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "29.3", active)
+
+	err = UseProtocVersion("1.0")
+	assert.ErrorIs(t, err, ErrProtocVersionNotInstalled)
+
+	err = UseProtocVersion("../versions/29.3")
+	assert.ErrorIs(t, err, ErrInvalidProtocVersion, "Версия не должна выходить за пределы каталога versions")
+}
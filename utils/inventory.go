@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Источники, из которых мог появиться найденный исполняемый файл.
+const (
+	OriginManaged        = "protocInstall"
+	OriginHomebrew       = "homebrew"
+	OriginPackageManager = "package manager"
+	OriginGoInstall      = "go install"
+	OriginManual         = "manual"
+	OriginUnknown        = "unknown"
+)
+
+// ToolBinary исполняемый файл protoc или плагина protoc-gen-*, найденный в PATH.
+type ToolBinary struct {
+	Name string
+	// Path путь в каталоге из PATH.
+	Path string
+	// RealPath путь с раскрытыми символическими ссылками.
+	RealPath string
+	// Version версия из вывода --version, пустая, если её не удалось получить.
+	Version string
+	// Origin вероятный источник установки.
+	Origin string
+	// Managed сообщает, что файл принадлежит установке protocInstall.
+	Managed bool
+	// Shadowed сообщает, что раньше в PATH найден другой файл с тем же именем.
+	Shadowed bool
+}
+
+// Inventory возвращает все protoc и protoc-gen-* из PATH в порядке поиска, с версией и вероятным источником.
+func (p *Platform) Inventory() ([]ToolBinary, error) {
+	managed, err := managedBinaries()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "protoc-gen-*"))
+		for _, name := range append([]string{"protoc"}, matches...) {
+			name = filepath.Base(name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var binaries []ToolBinary
+	for _, name := range names {
+		for i, path := range LookPathAll(name) {
+			binary := ToolBinary{
+				Name:     name,
+				Path:     path,
+				RealPath: realPath(path),
+				Shadowed: i > 0,
+			}
+			binary.Managed = managed[binary.RealPath]
+			binary.Origin = binaryOrigin(binary.RealPath, binary.Managed)
+			if output, err := p.Runner.Output(path, "--version"); err == nil {
+				binary.Version = parseProtocVersion(output)
+			}
+			binaries = append(binaries, binary)
+		}
+	}
+
+	return binaries, nil
+}
+
+// managedBinaries возвращает реальные пути исполняемых файлов, установленных protocInstall.
+func managedBinaries() (map[string]bool, error) {
+	manifests, err := ReadManifests()
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make(map[string]bool)
+	for _, manifest := range manifests {
+		for path := range manifest.Files {
+			if filepath.Base(filepath.Dir(path)) == "bin" {
+				managed[realPath(path)] = true
+			}
+		}
+	}
+
+	return managed, nil
+}
+
+// binaryOrigin определяет вероятный источник исполняемого файла по его реальному пути.
+func binaryOrigin(path string, managed bool) string {
+	if managed {
+		return OriginManaged
+	}
+
+	slashed := filepath.ToSlash(path)
+	if home, err := ProtocInstallHome(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return OriginManaged
+	}
+	if strings.Contains(slashed, "/Cellar/") || strings.HasPrefix(slashed, "/opt/homebrew/") ||
+		strings.HasPrefix(slashed, "/home/linuxbrew/") {
+		return OriginHomebrew
+	}
+	for _, dir := range goBinDirs() {
+		if filepath.Dir(path) == dir {
+			return OriginGoInstall
+		}
+	}
+	switch filepath.ToSlash(filepath.Dir(path)) {
+	case "/usr/bin", "/bin", "/usr/sbin":
+		return OriginPackageManager
+	case "/usr/local/bin":
+		return OriginManual
+	}
+
+	return OriginUnknown
+}
+
+// goBinDirs возвращает каталоги, в которые go install кладёт исполняемые файлы.
+func goBinDirs() []string {
+	var dirs []string
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		dirs = append(dirs, filepath.Clean(gobin))
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			gopath = filepath.Join(home, "go")
+		}
+	}
+	for _, dir := range filepath.SplitList(gopath) {
+		dirs = append(dirs, filepath.Join(dir, "bin"))
+	}
+
+	return dirs
+}
+
+// ShadowingBinary возвращает первый в PATH исполняемый файл с именем binary,
+// если это не сам binary. Пустая строка означает, что binary находится первым или его имени нет в PATH.
+func ShadowingBinary(binary string) string {
+	hits := LookPathAll(filepath.Base(binary))
+	if len(hits) == 0 || realPath(hits[0]) == realPath(binary) {
+		return ""
+	}
+
+	return hits[0]
+}
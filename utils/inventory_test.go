@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755))
+}
+
+func TestInventory(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+	t.Setenv("GOPATH", t.TempDir())
+
+	managed := t.TempDir()
+	writeExecutable(t, filepath.Join(managed, "bin", "protoc"))
+	sum, err := fileSHA256(filepath.Join(managed, "bin", "protoc"))
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(Manifest{
+		Tool: "protoc", Version: "29.3", Target: HistoryTargetSystem, Root: managed,
+		Files: map[string]string{filepath.Join(managed, "bin", "protoc"): sum},
+	}))
+
+	other := t.TempDir()
+	writeExecutable(t, filepath.Join(other, "protoc"))
+	goBin := filepath.Join(os.Getenv("GOPATH"), "bin")
+	writeExecutable(t, filepath.Join(goBin, "protoc-gen-go"))
+
+	t.Setenv("PATH", strings.Join([]string{other, filepath.Join(managed, "bin"), goBin}, string(os.PathListSeparator)))
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: &FakeRunner{Strict: true, Outputs: map[string]string{
+		filepath.Join(other, "protoc") + " --version":          "libprotoc 3.12.4\n",
+		filepath.Join(managed, "bin", "protoc") + " --version": "libprotoc 29.3\n",
+		filepath.Join(goBin, "protoc-gen-go") + " --version":   "protoc-gen-go v1.36.5\n",
+	}}}
+
+	binaries, err := p.Inventory()
+	require.NoError(t, err)
+	require.Len(t, binaries, 3)
+
+	assert.Equal(t, "protoc", binaries[0].Name)
+	assert.Equal(t, "3.12.4", binaries[0].Version)
+	assert.False(t, binaries[0].Managed)
+	assert.False(t, binaries[0].Shadowed)
+
+	assert.Equal(t, "29.3", binaries[1].Version)
+	assert.True(t, binaries[1].Managed)
+	assert.True(t, binaries[1].Shadowed)
+	assert.Equal(t, OriginManaged, binaries[1].Origin)
+
+	assert.Equal(t, "protoc-gen-go", binaries[2].Name)
+	assert.Equal(t, "1.36.5", binaries[2].Version)
+	assert.Equal(t, OriginGoInstall, binaries[2].Origin)
+
+	assert.Equal(t, filepath.Join(other, "protoc"), ShadowingBinary(filepath.Join(managed, "bin", "protoc")))
+	assert.Empty(t, ShadowingBinary(filepath.Join(other, "protoc")))
+}
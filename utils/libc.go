@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// Стандартные библиотеки C, с которыми может быть собран исполняемый файл.
+const (
+	LibcGlibc = "glibc"
+	LibcMusl  = "musl"
+)
+
+// ErrGlibcRequired возвращается, если релизный protoc не запускается на системе с musl.
+var ErrGlibcRequired = errors.New("protoc release binaries are linked against glibc and cannot run on this musl system")
+
+// glibcCompatPackage пакет Alpine, позволяющий запускать программы, собранные под glibc.
+const glibcCompatPackage = "gcompat"
+
+// glibcLoaderPatterns пути динамического загрузчика glibc на распространённых архитектурах.
+var glibcLoaderPatterns = []string{
+	"/lib/ld-linux*.so.*", "/lib64/ld-linux*.so.*", "/lib64/ld64.so.*",
+	"/usr/lib/ld-linux*.so.*", "/usr/lib64/ld-linux*.so.*", "/lib/*-linux-gnu*/ld-linux*.so.*",
+}
+
+// muslLoaderPatterns пути динамического загрузчика musl.
+var muslLoaderPatterns = []string{"/lib/ld-musl-*.so.1", "/usr/lib/ld-musl-*.so.1"}
+
+// DetectLibc определяет стандартную библиотеку C системы. Alpine всегда считается musl.
+// На остальных системах загрузчик glibc проверяется раньше загрузчика musl: на Debian и Ubuntu
+// с установленным пакетом musl присутствуют оба. Для систем, отличных от Linux, возвращается пустая строка.
+func (p *Platform) DetectLibc() string {
+	if p.OS != "linux" {
+		return ""
+	}
+	if p.IsAlpine() {
+		return LibcMusl
+	}
+	if p.hasLoader(glibcLoaderPatterns) {
+		return LibcGlibc
+	}
+	if p.hasLoader(muslLoaderPatterns) {
+		return LibcMusl
+	}
+
+	return LibcGlibc
+}
+
+// hasLoader сообщает, есть ли в корне платформы файл, подходящий под один из шаблонов.
+func (p *Platform) hasLoader(patterns []string) bool {
+	for _, pattern := range patterns {
+		if matches, _ := filepath.Glob(p.Path(pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EnsureGlibcCompat устанавливает слой совместимости с glibc, без которого релизный protoc не запускается на musl.
+func (p *Platform) EnsureGlibcCompat(distro LinuxDistribution) error {
+	manager, err := p.DetectPackageManager(distro)
+	if err != nil {
+		return err
+	}
+	if manager.Name() != "apk" {
+		return fmt.Errorf("%w: no glibc compatibility package is known for %s", ErrGlibcRequired, distro)
+	}
+
+	installed, err := manager.IsInstalled(glibcCompatPackage)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if installed {
+		return nil
+	}
+
+	log.Printf("Installing %s so that the glibc-linked protoc release can run on musl", glibcCompatPackage)
+	if err := manager.Install(glibcCompatPackage); err != nil {
+		return fmt.Errorf("%w: %w", ErrGlibcRequired, err)
+	}
+
+	return nil
+}
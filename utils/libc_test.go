@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLibc(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+	assert.Equal(t, LibcGlibc, p.DetectLibc())
+
+	loader := p.Path("/lib/ld-musl-x86_64.so.1")
+	require.NoError(t, os.MkdirAll(filepath.Dir(loader), 0o755))
+	require.NoError(t, os.WriteFile(loader, nil, 0o755))
+	assert.Equal(t, LibcMusl, p.DetectLibc())
+
+	p.OS = "darwin"
+	assert.Empty(t, p.DetectLibc())
+}
+
+func TestDetectLibcBothLoaders(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+	for _, loader := range []string{"/lib/ld-musl-x86_64.so.1", "/lib64/ld-linux-x86-64.so.2"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(p.Path(loader)), 0o755))
+		require.NoError(t, os.WriteFile(p.Path(loader), nil, 0o755))
+	}
+	assert.Equal(t, LibcGlibc, p.DetectLibc(), "Пакет musl на Debian не делает систему musl")
+
+	// gcompat на Alpine тоже ставит ld-linux, но система остаётся musl
+	require.NoError(t, os.MkdirAll(p.Path("/etc"), 0o755))
+	require.NoError(t, os.WriteFile(p.Path("/etc/os-release"), []byte("ID=alpine\nVERSION_ID=3.20.0\n"), 0o644))
+	assert.Equal(t, LibcMusl, p.DetectLibc())
+}
+
+func TestEnsureGlibcCompat(t *testing.T) {
+	fake := &FakePackageManager{ManagerName: "apk", Installed: map[string]string{"gcompat": "1.1.0"}}
+	p := &Platform{OS: "linux", Arch: "amd64", PackageManager: fake}
+	alpine := LinuxDistribution{ID: "alpine", Family: DistroFamilyAlpine}
+
+	require.NoError(t, p.EnsureGlibcCompat(alpine))
+	assert.Empty(t, fake.Calls, "Установленный gcompat не переустанавливается")
+
+	delete(fake.Installed, "gcompat")
+	fake.Err = errors.New("exit status 1")
+	err := p.EnsureGlibcCompat(alpine)
+	require.ErrorIs(t, err, ErrGlibcRequired)
+	assert.Equal(t, []string{"install gcompat"}, fake.Calls)
+
+	p.PackageManager = &FakePackageManager{ManagerName: "xbps"}
+	err = p.EnsureGlibcCompat(LinuxDistribution{ID: "void"})
+	assert.ErrorIs(t, err, ErrGlibcRequired)
+}
+
+func TestStageProtocTreeOnMusl(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir(), Runner: &FakeRunner{
+		Handle: func(command string, _ []string) ([]byte, error) {
+			if filepath.Base(command) == "protoc" {
+				return nil, errors.New("exec: no such file or directory")
+			}
+
+			return nil, nil
+		},
+	}}
+	loader := p.Path("/lib/ld-musl-x86_64.so.1")
+	require.NoError(t, os.MkdirAll(filepath.Dir(loader), 0o755))
+	require.NoError(t, os.WriteFile(loader, nil, 0o755))
+
+	staging := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(staging, "tree", "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(staging, "tree", "bin", "protoc"), nil, 0o644))
+
+	_, err := p.stageProtocTree("protoc.zip", "29.3", staging)
+	require.ErrorIs(t, err, ErrGlibcRequired)
+	assert.ErrorContains(t, err, "exec: no such file or directory", "Ошибка запуска не должна теряться")
+	assert.ErrorContains(t, err, "install gcompat")
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInstallLocked возвращается, если блокировку установки не удалось получить за отведённое время.
+var ErrInstallLocked = errors.New("another protocInstall run holds the install lock")
+
+// lockPollInterval интервал повторных попыток захватить блокировку.
+const lockPollInterval = 200 * time.Millisecond
+
+// InstallLock эксклюзивная межпроцессная блокировка операций установки и удаления.
+type InstallLock struct {
+	file *os.File
+}
+
+// InstallLockPath возвращает путь к файлу блокировки установки в домашнем каталоге протокола.
+// Эта блокировка защищает только данные пользователя: каталог версий, историю и кэш.
+func InstallLockPath() (string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, "install.lock"), nil
+}
+
+// systemLockDirs каталоги общей блокировки системной установки в порядке предпочтения.
+// Они доступны на запись всем пользователям, в отличие от /usr/local, поэтому запуски от разных
+// пользователей, а также с sudo и без него, находят одну и ту же блокировку.
+var systemLockDirs = []string{"/run/lock", "/var/lock", "/tmp"}
+
+// SystemInstallLockPath возвращает путь к общей для всех пользователей блокировке,
+// которая защищает /usr/local и пакетный менеджер.
+func (p *Platform) SystemInstallLockPath() string {
+	for _, dir := range systemLockDirs {
+		if info, err := os.Stat(p.Path(dir)); err == nil && info.IsDir() {
+			return filepath.Join(p.Path(dir), "protocinstall.lock")
+		}
+	}
+
+	return filepath.Join(p.Path("/tmp"), "protocinstall.lock")
+}
+
+// AcquireInstallLock захватывает блокировку установки, ожидая её освобождения не дольше timeout.
+// В файл блокировки записывается PID владельца, чтобы ожидающий процесс мог сообщить, кто её держит.
+func AcquireInstallLock(timeout time.Duration) (*InstallLock, error) {
+	return AcquireInstallLockContext(context.Background(), timeout)
+}
+
+// AcquireInstallLockContext захватывает блокировку как AcquireInstallLock и прекращает ожидание при отмене ctx.
+func AcquireInstallLockContext(ctx context.Context, timeout time.Duration) (*InstallLock, error) {
+	path, err := InstallLockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return acquireLock(ctx, path, timeout)
+}
+
+// AcquireSystemInstallLock захватывает общую блокировку системной установки как AcquireInstallLockContext.
+// Её должны держать все шаги, которые меняют /usr/local или вызывают пакетный менеджер.
+func (p *Platform) AcquireSystemInstallLock(ctx context.Context, timeout time.Duration) (*InstallLock, error) {
+	return acquireLock(ctx, p.SystemInstallLockPath(), timeout)
+}
+
+// openLockFile открывает файл блокировки. Каталоги общей блокировки доступны на запись всем, поэтому
+// символические ссылки не раскрываются, а открытый файл должен быть обычным файлом без других жёстких ссылок:
+// иначе подложенная ссылка на системный файл заставила бы процесс с правами root перезаписать его.
+// Созданный этим процессом файл делается доступным на запись всем, чтобы его могли открыть другие пользователи;
+// файл чужого пользователя открывается только на чтение, flock этого достаточно.
+func openLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL|lockOpenFlags, 0o666) //nolint:mnd
+	created := err == nil
+	if errors.Is(err, os.ErrExist) {
+		file, err = os.OpenFile(path, os.O_RDWR|lockOpenFlags, 0)
+		if errors.Is(err, os.ErrPermission) {
+			file, err = os.OpenFile(path, os.O_RDONLY|lockOpenFlags, 0)
+		}
+	}
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	info, err := file.Stat()
+	if err == nil {
+		err = checkLockFile(info)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("refusing to use lock file %s: %w", path, err)
+	}
+	if created {
+		_ = file.Chmod(0o666) //nolint:mnd
+	}
+
+	return file, nil
+}
+
+func acquireLock(ctx context.Context, path string, timeout time.Duration) (*InstallLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	file, err := openLockFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+
+		holder := lockHolder(path)
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w (PID %s, lock file %s)", ErrInstallLocked, holder, path)
+		}
+		if !waiting {
+			log.Printf("Waiting for another protocInstall run (PID %s) to finish", holder)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, fmt.Errorf("stopped waiting for the install lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &InstallLock{file: file}, nil
+}
+
+// Release освобождает блокировку установки.
+func (l *InstallLock) Release() error {
+	_ = l.file.Truncate(0)
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock install lock: %w", err)
+	}
+
+	return l.file.Close() //nolint:wrapcheck
+}
+
+// lockHolder возвращает PID процесса, записанный в файл блокировки.
+func lockHolder(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(content)) == "" {
+		return "unknown"
+	}
+
+	return strings.TrimSpace(string(content))
+}
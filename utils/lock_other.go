@@ -0,0 +1,27 @@
+//go:build !unix
+
+package utils
+
+import (
+	"errors"
+	"os"
+)
+
+const lockOpenFlags = 0
+
+func checkLockFile(info os.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return errors.New("not a regular file")
+	}
+
+	return nil
+}
+
+// На платформах без flock установщик не поддерживается, блокировка не выполняется.
+func tryLockFile(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
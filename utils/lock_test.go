@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireInstallLock(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	lock, err := AcquireInstallLock(time.Second)
+	require.NoError(t, err)
+
+	_, err = AcquireInstallLock(300 * time.Millisecond)
+	require.ErrorIs(t, err, ErrInstallLocked)
+	assert.Contains(t, err.Error(), "PID "+strconv.Itoa(os.Getpid()), "Ошибка должна содержать PID владельца блокировки")
+
+	require.NoError(t, lock.Release())
+
+	lock, err = AcquireInstallLock(time.Second)
+	require.NoError(t, err, "После освобождения блокировку можно захватить снова")
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquireInstallLockContextCancelled(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	lock, err := AcquireInstallLock(time.Second)
+	require.NoError(t, err)
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = AcquireInstallLockContext(ctx, time.Minute)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second, "Отмена контекста должна прекращать ожидание блокировки")
+}
+
+func TestSystemInstallLock(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+	assert.Equal(t, p.Path("/tmp/protocinstall.lock"), p.SystemInstallLockPath())
+
+	require.NoError(t, os.MkdirAll(p.Path("/run/lock"), 0o755))
+	assert.Equal(t, p.Path("/run/lock/protocinstall.lock"), p.SystemInstallLockPath())
+
+	lock, err := p.AcquireSystemInstallLock(context.Background(), time.Second)
+	require.NoError(t, err)
+
+	// Другой пользователь получает свой домашний каталог, но ту же системную блокировку
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+	_, err = p.AcquireSystemInstallLock(context.Background(), 300*time.Millisecond)
+	require.ErrorIs(t, err, ErrInstallLocked)
+	user, err := AcquireInstallLock(time.Second)
+	require.NoError(t, err, "Пользовательская блокировка не зависит от системной")
+	require.NoError(t, user.Release())
+
+	info, err := os.Stat(p.SystemInstallLockPath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o666), info.Mode().Perm(), "Файл блокировки должен открываться другими пользователями")
+	require.NoError(t, lock.Release())
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockOpenFlags запрещает открывать файл блокировки через символическую ссылку.
+const lockOpenFlags = syscall.O_NOFOLLOW
+
+// checkLockFile отклоняет всё, кроме обычного файла с единственной жёсткой ссылкой.
+func checkLockFile(info os.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", info.Mode())
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Nlink != 1 {
+		return fmt.Errorf("file has %d hard links", st.Nlink)
+	}
+
+	return nil
+}
+
+func tryLockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err //nolint:wrapcheck
+	}
+
+	return true, nil
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN) //nolint:wrapcheck
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemInstallLockRefusesLinks(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+	require.NoError(t, os.MkdirAll(p.Path("/run/lock"), 0o755))
+	victim := filepath.Join(t.TempDir(), "shadow")
+	require.NoError(t, os.WriteFile(victim, []byte("root:secret\n"), 0o600))
+
+	require.NoError(t, os.Symlink(victim, p.SystemInstallLockPath()))
+	_, err := p.AcquireSystemInstallLock(context.Background(), time.Second)
+	require.Error(t, err, "Символическая ссылка вместо файла блокировки должна отклоняться")
+
+	require.NoError(t, os.Remove(p.SystemInstallLockPath()))
+	require.NoError(t, os.Link(victim, p.SystemInstallLockPath()))
+	_, err = p.AcquireSystemInstallLock(context.Background(), time.Second)
+	require.ErrorContains(t, err, "hard links", "Жёсткая ссылка на чужой файл должна отклоняться")
+
+	content, err := os.ReadFile(victim)
+	require.NoError(t, err)
+	assert.Equal(t, "root:secret\n", string(content), "Файл по ссылке не должен перезаписываться")
+	info, err := os.Stat(victim)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Права файла по ссылке не должны меняться")
+
+	require.NoError(t, os.Remove(p.SystemInstallLockPath()))
+	require.NoError(t, os.Mkdir(p.SystemInstallLockPath(), 0o755))
+	_, err = p.AcquireSystemInstallLock(context.Background(), time.Second)
+	require.Error(t, err, "Каталог вместо файла блокировки должен отклоняться")
+}
+
+func TestSystemInstallLockKeepsExistingMode(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64", Root: t.TempDir()}
+	require.NoError(t, os.MkdirAll(p.Path("/run/lock"), 0o755))
+	require.NoError(t, os.WriteFile(p.SystemInstallLockPath(), nil, 0o644))
+
+	lock, err := p.AcquireSystemInstallLock(context.Background(), time.Second)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+
+	info, err := os.Stat(p.SystemInstallLockPath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm(), "Права файла, созданного не этим процессом, не меняются")
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoManifests возвращается, если ни одна управляемая установка ещё не записала манифест.
+var ErrNoManifests = errors.New("no managed installs recorded")
+
+// ErrVerificationFailed возвращается, если установленные файлы или PATH расходятся с манифестом.
+var ErrVerificationFailed = errors.New("installation does not match the manifest")
+
+// Manifest перечисляет файлы управляемой установки и их контрольные суммы.
+type Manifest struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	// Target цель установки: HistoryTargetSystem или HistoryTargetVersions.
+	Target string `json:"target"`
+	// Root каталог, в который выполнена установка. Для HistoryTargetSystem путь задан относительно
+	// корня Platform, для HistoryTargetVersions это путь в каталоге версий.
+	Root string `json:"root"`
+	// Files сопоставляет абсолютный путь установленного файла (в тех же координатах, что Root) и его SHA-256.
+	Files       map[string]string `json:"files"`
+	InstalledAt time.Time         `json:"installed_at"` //nolint:tagliatelle
+}
+
+// Binary возвращает путь к исполняемому файлу protoc из манифеста.
+func (m Manifest) Binary() string {
+	return filepath.Join(m.Root, "bin", "protoc")
+}
+
+// VerifyReport результат проверки установленных файлов и PATH.
+type VerifyReport struct {
+	// Manifests проверенные манифесты.
+	Manifests []Manifest
+	// Modified файлы, содержимое которых отличается от манифеста.
+	Modified []string
+	// Missing файлы из манифеста, которых нет на диске.
+	Missing []string
+	// PathProtoc первый protoc, найденный в PATH.
+	PathProtoc string
+	// Foreign исполняемые файлы protoc в PATH, не принадлежащие ни одной управляемой установке.
+	Foreign []string
+	// Shadowed сообщает, что первым в PATH находится не управляемый protoc.
+	Shadowed bool
+	// WrongVersion управляемые protoc, которые не запускаются или сообщают версию, отличную от манифеста.
+	WrongVersion []string
+}
+
+// OK сообщает, что расхождений не найдено.
+func (r VerifyReport) OK() bool {
+	return len(r.Modified) == 0 && len(r.Missing) == 0 && len(r.WrongVersion) == 0 && !r.Shadowed
+}
+
+// manifestsDir возвращает каталог, в котором хранятся манифесты установок.
+func manifestsDir() (string, error) {
+	home, err := ProtocInstallHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, "manifests"), nil
+}
+
+// buildManifest вычисляет контрольные суммы файлов дерева tree, которые после установки окажутся в root.
+func buildManifest(target, version, tree, root string, paths []string) (Manifest, error) {
+	manifest := Manifest{
+		Tool:        "protoc",
+		Version:     version,
+		Target:      target,
+		Root:        root,
+		Files:       make(map[string]string),
+		InstalledAt: time.Now().UTC(),
+	}
+
+	for _, managed := range paths {
+		err := filepath.WalkDir(filepath.Join(tree, managed), func(path string, d os.DirEntry, err error) error {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(tree, path)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			sum, err := fileSHA256(path)
+			if err != nil {
+				return err
+			}
+			manifest.Files[filepath.Join(root, rel)] = sum
+
+			return nil
+		})
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to build manifest: %w", err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// WriteManifest сохраняет манифест установки, заменяя предыдущий манифест той же цели.
+func WriteManifest(manifest Manifest) error {
+	dir, err := manifestsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to create manifests directory: %w", err)
+	}
+
+	name := manifest.Target
+	if manifest.Target == HistoryTargetVersions {
+		name += "-" + manifest.Version
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), content, 0o644); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// ReadManifests возвращает манифесты всех управляемых установок.
+func ReadManifests() ([]Manifest, error) {
+	dir, err := manifestsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	sort.Strings(paths)
+
+	manifests := make([]Manifest, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// Verify пересчитывает контрольные суммы установленных файлов по манифестам, запускает управляемые protoc
+// с --version и проверяет, что первым в PATH находится управляемый protoc. Системные установки
+// и каталоги PATH ищутся относительно корня платформы.
+func (p *Platform) Verify() (VerifyReport, error) {
+	manifests, err := ReadManifests()
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	if len(manifests) == 0 {
+		return VerifyReport{}, ErrNoManifests
+	}
+
+	report := VerifyReport{Manifests: manifests}
+	managed := make(map[string]bool)
+	for _, manifest := range manifests {
+		binary := p.manifestPath(manifest, manifest.Binary())
+		managed[realPath(binary)] = true
+
+		paths := make([]string, 0, len(manifest.Files))
+		for path := range manifest.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			sum, err := fileSHA256(p.manifestPath(manifest, path))
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				report.Missing = append(report.Missing, path)
+			case err != nil:
+				return VerifyReport{}, err
+			case sum != manifest.Files[path]:
+				report.Modified = append(report.Modified, path)
+			}
+		}
+
+		if _, ok := manifest.Files[manifest.Binary()]; !ok {
+			continue
+		}
+		if _, err := os.Stat(binary); err != nil {
+			continue
+		}
+		output, err := p.Runner.Output(binary, "--version")
+		if err != nil || CompareVersions(ParseProtocReleaseVersion(output), manifest.Version) != 0 {
+			report.WrongVersion = append(report.WrongVersion, manifest.Binary())
+		}
+	}
+
+	for i, path := range p.lookPathAll("protoc") {
+		isManaged := managed[realPath(path)]
+		if i == 0 {
+			report.PathProtoc = path
+			report.Shadowed = !isManaged
+		}
+		if !isManaged {
+			report.Foreign = append(report.Foreign, path)
+		}
+	}
+
+	return report, nil
+}
+
+// manifestPath возвращает путь к файлу манифеста на диске: пути системной установки разрешаются
+// относительно корня платформы.
+func (p *Platform) manifestPath(manifest Manifest, path string) string {
+	if manifest.Target == HistoryTargetSystem {
+		return p.Path(path)
+	}
+
+	return path
+}
+
+// lookPathAll как LookPathAll, но ищет в каталогах PATH относительно корня платформы.
+func (p *Platform) lookPathAll(name string) []string {
+	var found []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		switch {
+		case dir == "":
+			dir = "."
+		case filepath.IsAbs(dir):
+			dir = p.Path(dir)
+		}
+		if path := lookPath(dir, name); path != "" && !slices.Contains(found, path) {
+			found = append(found, path)
+		}
+	}
+
+	return found
+}
+
+// LookPathAll возвращает все исполняемые файлы name в каталогах PATH в порядке поиска.
+func LookPathAll(name string) []string {
+	var found []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		if path := lookPath(dir, name); path != "" && !slices.Contains(found, path) {
+			found = append(found, path)
+		}
+	}
+
+	return found
+}
+
+// lookPath возвращает путь к исполняемому файлу name в каталоге dir или пустую строку.
+func lookPath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return ""
+	}
+
+	return path
+}
+
+// realPath возвращает путь с раскрытыми символическими ссылками или исходный путь при ошибке.
+func realPath(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	return strings.TrimSuffix(resolved, string(filepath.Separator))
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: &FakeRunner{Handle: func(string, []string) ([]byte, error) {
+		return []byte("libprotoc 29.3\n"), nil
+	}}}
+	_, err := p.Verify()
+	require.ErrorIs(t, err, ErrNoManifests)
+
+	tree := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tree, "bin"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tree, "include", "google", "protobuf"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tree, "bin", "protoc"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tree, "include", "google", "protobuf", "any.proto"), []byte("any"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tree, "include", "google", "protobuf", "empty.proto"), []byte("empty"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tree, "readme.txt"), []byte("not managed"), 0o644))
+
+	prefix := t.TempDir()
+	manifest, err := buildManifest(HistoryTargetSystem, "29.3", tree, prefix, protocManagedPaths)
+	require.NoError(t, err)
+	assert.Len(t, manifest.Files, 3, "Файлы вне управляемых путей не попадают в манифест")
+	require.NoError(t, WriteManifest(manifest))
+	require.NoError(t, os.Rename(filepath.Join(tree, "bin"), filepath.Join(prefix, "bin")))
+	require.NoError(t, os.Rename(filepath.Join(tree, "include"), filepath.Join(prefix, "include")))
+
+	foreign := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(foreign, "protoc"), []byte("#!/bin/sh\n"), 0o755))
+
+	t.Setenv("PATH", filepath.Join(prefix, "bin")+string(os.PathListSeparator)+foreign)
+	report, err := p.Verify()
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, filepath.Join(prefix, "bin", "protoc"), report.PathProtoc)
+	assert.Equal(t, []string{filepath.Join(foreign, "protoc")}, report.Foreign)
+
+	anyProto := filepath.Join(prefix, "include", "google", "protobuf", "any.proto")
+	emptyProto := filepath.Join(prefix, "include", "google", "protobuf", "empty.proto")
+	require.NoError(t, os.WriteFile(anyProto, []byte("changed"), 0o644))
+	require.NoError(t, os.Remove(emptyProto))
+
+	t.Setenv("PATH", foreign+string(os.PathListSeparator)+filepath.Join(prefix, "bin"))
+	report, err = p.Verify()
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{anyProto}, report.Modified)
+	assert.Equal(t, []string{emptyProto}, report.Missing)
+	assert.True(t, report.Shadowed)
+	assert.Equal(t, filepath.Join(foreign, "protoc"), report.PathProtoc)
+}
+
+func TestVerifyAlternateRoot(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	tree := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tree, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tree, "bin", "protoc"), []byte("#!/bin/sh\n"), 0o755))
+	manifest, err := buildManifest(HistoryTargetSystem, "29.3", tree, "/usr/local", protocManagedPaths)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(manifest))
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "local"), 0o755))
+	require.NoError(t, os.Rename(filepath.Join(tree, "bin"), filepath.Join(root, "usr", "local", "bin")))
+	t.Setenv("PATH", "/usr/local/bin"+string(os.PathListSeparator)+"/usr/bin")
+
+	runner := &FakeRunner{Strict: true, Outputs: map[string]string{
+		filepath.Join(root, "usr", "local", "bin", "protoc") + " --version": "libprotoc 3.28.2\n",
+	}}
+	p := &Platform{OS: "linux", Arch: "amd64", Root: root, Runner: runner}
+	report, err := p.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, report.Missing, "Файлы системной установки ищутся относительно корня платформы")
+	assert.Empty(t, report.Modified)
+	assert.Equal(t, filepath.Join(root, "usr", "local", "bin", "protoc"), report.PathProtoc)
+	assert.False(t, report.Shadowed)
+	assert.Equal(t, []string{"/usr/local/bin/protoc"}, report.WrongVersion, "protoc сообщает версию, отличную от манифеста")
+	assert.False(t, report.OK())
+
+	runner.Outputs[filepath.Join(root, "usr", "local", "bin", "protoc")+" --version"] = "libprotoc 29.3\n"
+	report, err = p.Verify()
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrLocalArchiveNotFound возвращается, если в локальном источнике нет архива protoc для текущей платформы.
+var ErrLocalArchiveNotFound = errors.New("protoc archive not found in local source")
+
+var protocArchiveVersionRegexp = regexp.MustCompile(`^protoc-(\d+(?:\.\d+)*(?:-rc-?\d+)?)-`)
+
+// mirrorLayouts перечисляет каталоги внутри зеркала, в которых могут лежать каталоги релизов v<version>.
+// Поддерживается как дерево releases/download/v<version>/ с GitHub, так и его укороченные варианты.
+var mirrorLayouts = []string{"", "download", filepath.Join("releases", "download")}
+
+// ResolveLocalProtocArchive находит архив protoc в локальном источнике без обращения к сети.
+// source может быть путём к zip-архиву релиза или к каталогу-зеркалу, повторяющему дерево релизов GitHub.
+// Если version не указана, она определяется по имени архива или выбирается наибольшая версия в зеркале.
+// Возвращает путь к архиву и его версию.
+func (p *Platform) ResolveLocalProtocArchive(source, version string) (string, string, error) {
+	version = strings.TrimPrefix(version, "v")
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open local source: %w", err)
+	}
+
+	if !info.IsDir() {
+		if version == "" {
+			match := protocArchiveVersionRegexp.FindStringSubmatch(filepath.Base(source))
+			if match == nil {
+				return "", "", fmt.Errorf("cannot detect protoc version from %s, pass it explicitly", source)
+			}
+			version = match[1]
+		}
+
+		return source, version, nil
+	}
+
+	if version == "" {
+		version, err = latestMirrorVersion(source)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	assets, err := protocAssetCandidates(version, p.OS, p.Arch)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, asset := range assets {
+		for _, layout := range mirrorLayouts {
+			candidate := filepath.Join(source, layout, "v"+version, asset)
+			if fileExists(candidate) {
+				return candidate, version, nil
+			}
+		}
+		// Плоский каталог с архивами
+		if candidate := filepath.Join(source, asset); fileExists(candidate) {
+			return candidate, version, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: %s in %s", ErrLocalArchiveNotFound, strings.Join(assets, ", "), source)
+}
+
+// latestMirrorVersion возвращает наибольшую версию среди каталогов v<version> в зеркале.
+func latestMirrorVersion(mirror string) (string, error) {
+	var latest string
+	for _, layout := range mirrorLayouts {
+		entries, err := os.ReadDir(filepath.Join(mirror, layout))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "v") {
+				continue
+			}
+			version := strings.TrimPrefix(entry.Name(), "v")
+			if latest == "" || CompareVersions(version, latest) > 0 {
+				latest = version
+			}
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("%w: no release directories in %s", ErrLocalArchiveNotFound, mirror)
+	}
+
+	return latest, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
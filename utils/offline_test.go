@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeArchive(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("zip"), 0o644))
+}
+
+func TestResolveLocalProtocArchive_File(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "amd64"}
+	archive := filepath.Join(t.TempDir(), "protoc-29.3-linux-x86_64.zip")
+	writeArchive(t, archive)
+
+	path, version, err := p.ResolveLocalProtocArchive(archive, "")
+	require.NoError(t, err)
+	assert.Equal(t, archive, path)
+	assert.Equal(t, "29.3", version, "Версия должна определяться по имени архива")
+
+	_, version, err = p.ResolveLocalProtocArchive(archive, "v29.2")
+	require.NoError(t, err)
+	assert.Equal(t, "29.2", version, "Явно указанная версия имеет приоритет")
+
+	other := filepath.Join(t.TempDir(), "protoc.zip")
+	writeArchive(t, other)
+	_, _, err = p.ResolveLocalProtocArchive(other, "")
+	assert.Error(t, err)
+}
+
+func TestResolveLocalProtocArchive_Mirror(t *testing.T) {
+	p := &Platform{OS: "linux", Arch: "arm64"}
+	mirror := t.TempDir()
+	for _, version := range []string{"3.21", "29.3", "29.3-rc1"} {
+		assets, err := protocAssetCandidates(version, p.OS, p.Arch)
+		require.NoError(t, err)
+		writeArchive(t, filepath.Join(mirror, "releases", "download", "v"+version, assets[0]))
+	}
+
+	path, version, err := p.ResolveLocalProtocArchive(mirror, "")
+	require.NoError(t, err)
+	assert.Equal(t, "29.3", version, "Без версии выбирается наибольшая, релиз старше release candidate")
+	assert.FileExists(t, path)
+
+	_, version, err = p.ResolveLocalProtocArchive(mirror, "3.21")
+	require.NoError(t, err)
+	assert.Equal(t, "3.21", version)
+
+	_, _, err = p.ResolveLocalProtocArchive(mirror, "28.0")
+	assert.ErrorIs(t, err, ErrLocalArchiveNotFound)
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// osReleasePaths перечисляет расположения os-release в порядке, заданном спецификацией freedesktop:
+// /etc/os-release имеет приоритет, /usr/lib/os-release используется, если его нет.
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// OSRelease переменные из файла os-release.
+type OSRelease map[string]string
+
+// ReadOSRelease читает os-release относительно корня root. Альтернативный корень позволяет
+// разбирать chroot или распакованный образ контейнера.
+func ReadOSRelease(root string) (OSRelease, error) {
+	for _, name := range osReleasePaths {
+		file, err := os.Open(filepath.Join(root, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer file.Close()
+
+		release, err := ParseOSRelease(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		return release, nil
+	}
+
+	return nil, fmt.Errorf("os-release not found in %s: %w", strings.Join(osReleasePaths, ", "), os.ErrNotExist)
+}
+
+// ParseOSRelease разбирает os-release: строки VAR=value, комментарии и пустые строки.
+// Значения могут быть в одинарных или двойных кавычках и содержать экранирование в стиле shell.
+// Строки, которые не удалось разобрать, пропускаются, как того требует спецификация.
+func ParseOSRelease(r io.Reader) (OSRelease, error) {
+	release := make(OSRelease)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok || !isOSReleaseKey(key) {
+			continue
+		}
+		value, ok := unquoteShellValue(raw)
+		if !ok {
+			continue
+		}
+		release[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return release, nil
+}
+
+// isOSReleaseKey проверяет, что имя переменной состоит из латинских букв, цифр и подчёркиваний.
+func isOSReleaseKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unquoteShellValue раскрывает значение присваивания shell: кавычки и экранирование \", \\, \$, \`.
+// В одинарных кавычках экранирование не действует. Возвращает false, если кавычка не закрыта.
+func unquoteShellValue(raw string) (string, bool) {
+	var value strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range raw {
+		switch {
+		case escaped:
+			// Внутри двойных кавычек обратная косая черта экранирует только специальные символы
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				value.WriteRune('\\')
+			}
+			value.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && (r == ' ' || r == '\t'):
+			// Незакавыченный пробел завершает значение, дальше может идти только комментарий
+			return value.String(), true
+		default:
+			value.WriteRune(r)
+		}
+	}
+	if quote != 0 || escaped {
+		return "", false
+	}
+
+	return value.String(), true
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	content := strings.Join([]string{
+		"# comment",
+		"",
+		`NAME="Fedora Linux"`,
+		"ID=fedora",
+		`VERSION='41 (Workstation Edition)'`,
+		`PRETTY_NAME="Fedora \"Forty One\" \$HOME \\ \x"`,
+		`VARIANT=Workstation\ Edition`,
+		`SUPPORT_END='2025-05-13' # trailing comment`,
+		`HOME_URL="https://fedoraproject.org/`,
+		"not a variable",
+		"BAD-KEY=value",
+		`  LOGO=fedora-logo-icon  `,
+	}, "\n")
+
+	release, err := ParseOSRelease(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, OSRelease{
+		"NAME":        "Fedora Linux",
+		"ID":          "fedora",
+		"VERSION":     "41 (Workstation Edition)",
+		"PRETTY_NAME": `Fedora "Forty One" $HOME \ \x`,
+		"VARIANT":     "Workstation Edition",
+		"SUPPORT_END": "2025-05-13",
+		"LOGO":        "fedora-logo-icon",
+	}, release, "Незакрытые кавычки и неверные имена пропускаются")
+}
+
+func TestReadOSRelease(t *testing.T) {
+	root := t.TempDir()
+	_, err := ReadOSRelease(root)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "lib"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "usr", "lib", "os-release"), []byte("ID='alpine'\n"), 0o644))
+	release, err := ReadOSRelease(root)
+	require.NoError(t, err)
+	assert.Equal(t, "alpine", release["ID"], "Без /etc/os-release используется /usr/lib/os-release")
+
+	p := &Platform{OS: "linux", Arch: "amd64", Root: root}
+	assert.True(t, p.IsAlpine())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "etc", "os-release"), []byte("ID=debian\n"), 0o644))
+	release, err = ReadOSRelease(root)
+	require.NoError(t, err)
+	assert.Equal(t, "debian", release["ID"], "/etc/os-release имеет приоритет")
+	assert.False(t, p.IsAlpine())
+}
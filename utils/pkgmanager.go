@@ -0,0 +1,572 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrNoPackageManager возвращается, если для дистрибутива не найден поддерживаемый пакетный менеджер.
+var ErrNoPackageManager = errors.New("no supported package manager found")
+
+// ErrRemovalDeclined возвращается, если пользователь не подтвердил удаление системного пакета.
+var ErrRemovalDeclined = errors.New("removal of the system package was declined")
+
+// PackageManager системный пакетный менеджер дистрибутива.
+type PackageManager interface {
+	// Name имя менеджера, например apt или dnf.
+	Name() string
+	// ProtocPackage имя пакета, в котором дистрибутив поставляет protoc.
+	ProtocPackage() string
+	// IsInstalled сообщает, установлен ли пакет.
+	IsInstalled(pkg string) (bool, error)
+	// InstalledVersion возвращает upstream-версию установленного пакета без epoch и номера сборки.
+	InstalledVersion(pkg string) (string, error)
+	// CandidateVersion возвращает upstream-версию пакета, которую установит Install.
+	CandidateVersion(pkg string) (string, error)
+	// Install устанавливает пакет.
+	Install(pkg string) error
+	// Remove удаляет пакет.
+	Remove(pkg string) error
+	// DependentPackages возвращает пакеты, которые Remove удалит вместе с pkg. Набор определяется
+	// пробным удалением, система при этом не изменяется.
+	DependentPackages(pkg string) ([]string, error)
+}
+
+// systemPackageManager реализует PackageManager через команды менеджера.
+type systemPackageManager struct {
+	p *Platform
+	// name имя менеджера и его исполняемого файла.
+	name string
+	// protocPackage имя пакета с protoc.
+	protocPackage string
+	// install и remove аргументы команд установки и удаления, имя пакета добавляется в конец.
+	install []string
+	remove  []string
+	// dryRun аргументы пробного удаления, имя пакета добавляется в конец. dryRunRoot требует для него прав root.
+	dryRun     []string
+	dryRunRoot bool
+	// removed извлекает имена удаляемых пакетов из вывода пробного удаления.
+	removed func(output []byte) []string
+	// query возвращает сырую версию установленного пакета или пустую строку, если пакет не установлен.
+	query func(p *Platform, pkg string) string
+	// candidate возвращает сырую версию пакета в репозиториях или пустую строку, если пакета нет.
+	candidate func(p *Platform, pkg string) string
+}
+
+func (m *systemPackageManager) Name() string { return m.name }
+
+func (m *systemPackageManager) ProtocPackage() string { return m.protocPackage }
+
+func (m *systemPackageManager) IsInstalled(pkg string) (bool, error) {
+	return m.query(m.p, pkg) != "", nil
+}
+
+func (m *systemPackageManager) InstalledVersion(pkg string) (string, error) {
+	version := m.query(m.p, pkg)
+	if version == "" {
+		return "", fmt.Errorf("package %s is not installed", pkg)
+	}
+
+	return upstreamVersion(version), nil
+}
+
+func (m *systemPackageManager) CandidateVersion(pkg string) (string, error) {
+	version := m.candidate(m.p, pkg)
+	if version == "" {
+		return "", fmt.Errorf("package %s is not available from %s", pkg, m.name)
+	}
+
+	return upstreamVersion(version), nil
+}
+
+func (m *systemPackageManager) Install(pkg string) error {
+	run, err := m.p.privilegedRunner()
+	if err != nil {
+		return err
+	}
+	if err := run(m.name, append(m.install, pkg)...); err != nil {
+		return fmt.Errorf("failed to install %s with %s: %w", pkg, m.name, err)
+	}
+
+	return nil
+}
+
+func (m *systemPackageManager) Remove(pkg string) error {
+	run, err := m.p.privilegedRunner()
+	if err != nil {
+		return err
+	}
+	if err := run(m.name, append(m.remove, pkg)...); err != nil {
+		return fmt.Errorf("failed to remove %s with %s: %w", pkg, m.name, err)
+	}
+
+	return nil
+}
+
+func (m *systemPackageManager) DependentPackages(pkg string) ([]string, error) {
+	output := m.p.Runner.Output
+	if m.dryRunRoot {
+		var err error
+		if output, err = m.p.privilegedOutput(); err != nil {
+			return nil, err
+		}
+	}
+
+	// dnf и yum с --assumeno завершаются ошибкой, но список пакетов уже выведен
+	out, err := output(m.name, append(m.dryRun, pkg)...)
+	removed := m.removed(out)
+	if err != nil && len(removed) == 0 {
+		return nil, fmt.Errorf("failed to simulate removal of %s with %s: %w", pkg, m.name, err)
+	}
+
+	dependents := []string{}
+	for _, name := range removed {
+		if name != pkg {
+			dependents = append(dependents, name)
+		}
+	}
+
+	return dependents, nil
+}
+
+// newPackageManager возвращает менеджер с именем name.
+func (p *Platform) newPackageManager(name string) PackageManager {
+	switch name {
+	case "apt-get":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protobuf-compiler",
+			install: []string{"install", "-y"}, remove: []string{"remove", "-y"}, query: queryDpkg, candidate: candidateApt,
+			dryRun: []string{"-s", "remove"}, removed: removedApt,
+		}
+	case "dnf":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protobuf-compiler",
+			install: []string{"install", "-y"}, remove: []string{"remove", "-y"}, query: queryRPM, candidate: candidateDnf,
+			dryRun: []string{"remove", "--assumeno"}, dryRunRoot: true, removed: removedDnf,
+		}
+	case "yum":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protobuf-compiler",
+			install: []string{"install", "-y"}, remove: []string{"remove", "-y"}, query: queryRPM, candidate: candidateYum,
+			dryRun: []string{"remove", "--assumeno"}, dryRunRoot: true, removed: removedDnf,
+		}
+	case "zypper":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protobuf",
+			install: []string{"--non-interactive", "install"}, remove: []string{"--non-interactive", "remove"}, query: queryRPM,
+			candidate: candidateZypper, dryRun: []string{"--non-interactive", "remove", "--dry-run"}, dryRunRoot: true,
+			removed: removedZypper,
+		}
+	case "apk":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protoc",
+			install: []string{"add"}, remove: []string{"del"}, query: queryApk, candidate: candidateApk,
+			dryRun: []string{"del", "--simulate"}, removed: removedApk,
+		}
+	case "pacman":
+		return &systemPackageManager{
+			p: p, name: name, protocPackage: "protobuf",
+			install: []string{"-S", "--noconfirm"}, remove: []string{"-R", "--noconfirm"}, query: queryPacman,
+			candidate: candidatePacman, dryRun: []string{"-R", "--print", "--print-format", "%n"}, removed: removedPacman,
+		}
+	}
+
+	return nil
+}
+
+// familyPackageManagers перечисляет менеджеры семейства в порядке предпочтения.
+var familyPackageManagers = map[string][]string{
+	DistroFamilyDebian: {"apt-get"},
+	DistroFamilyRHEL:   {"dnf", "yum"},
+	DistroFamilySUSE:   {"zypper"},
+	DistroFamilyAlpine: {"apk"},
+	DistroFamilyArch:   {"pacman"},
+}
+
+// allPackageManagers порядок проверки менеджеров для дистрибутивов неизвестного семейства.
+var allPackageManagers = []string{"apt-get", "dnf", "yum", "zypper", "apk", "pacman"}
+
+// DetectPackageManager возвращает пакетный менеджер дистрибутива.
+// Если в Platform задан PackageManager, используется он. Когда семейству подходит
+// несколько менеджеров (dnf и yum) или семейство неизвестно, выбирается первый доступный.
+func (p *Platform) DetectPackageManager(distro LinuxDistribution) (PackageManager, error) {
+	if p.PackageManager != nil {
+		return p.PackageManager, nil
+	}
+
+	candidates, ok := familyPackageManagers[distro.Family]
+	if ok && len(candidates) == 1 {
+		return p.newPackageManager(candidates[0]), nil
+	}
+	if !ok {
+		candidates = allPackageManagers
+	}
+	for _, name := range candidates {
+		if _, err := p.Runner.Output(name, "--version"); err == nil {
+			return p.newPackageManager(name), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w for %s", ErrNoPackageManager, distro)
+}
+
+// SystemPackage пакет protobuf, установленный пакетным менеджером дистрибутива.
+type SystemPackage struct {
+	Manager PackageManager
+	Name    string
+	Version string
+	// Dependents пакеты, которые менеджер удалит вместе с этим. nil, если их не удалось определить.
+	Dependents []string
+}
+
+// String возвращает описание пакета для вывода пользователю.
+func (s SystemPackage) String() string {
+	return fmt.Sprintf("%s %s (%s)", s.Name, s.Version, s.Manager.Name())
+}
+
+// RemovePackageManagerProtobuf удаляет protobuf, установленный пакетным менеджером дистрибутива distro,
+// без подтверждения. distro значение ID из os-release, как его возвращает DetectLinuxDistribution.
+//
+// Deprecated: используйте Platform.RemovePackageManagerProtobuf.
+func RemovePackageManagerProtobuf(distro string) error {
+	return HostPlatform().RemovePackageManagerProtobuf(
+		LinuxDistribution{ID: distro, Family: distroFamily(distro, nil)},
+		func(SystemPackage) bool { return true },
+	)
+}
+
+// RemovePackageManagerProtobuf удаляет protobuf, установленный пакетным менеджером дистрибутива.
+// Перед удалением вызывается confirm с пакетами, которые будут удалены вместе с protobuf; если он возвращает false, пакет остаётся и возвращается ErrRemovalDeclined.
+func (p *Platform) RemovePackageManagerProtobuf(distro LinuxDistribution, confirm func(SystemPackage) bool) error {
+	manager, err := p.DetectPackageManager(distro)
+	if err != nil {
+		return fmt.Errorf("unsupported distribution: %w", err)
+	}
+
+	pkg := manager.ProtocPackage()
+	installed, err := manager.IsInstalled(pkg)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if !installed {
+		return nil
+	}
+
+	version, err := manager.InstalledVersion(pkg)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	dependents, err := manager.DependentPackages(pkg)
+	if err != nil {
+		log.Printf("Failed to determine packages that depend on %s: %v", pkg, err)
+	}
+	systemPackage := SystemPackage{Manager: manager, Name: pkg, Version: version, Dependents: dependents}
+	if !confirm(systemPackage) {
+		return fmt.Errorf("%w: %s", ErrRemovalDeclined, systemPackage)
+	}
+
+	return manager.Remove(pkg) //nolint:wrapcheck
+}
+
+// InstallPackageManagerProtoc устанавливает protoc пакетным менеджером дистрибутива,
+// если версия пакета в репозиториях удовлетворяет pin. Возвращает false, если пакет недоступен
+// или его версия не подходит; в этом случае система не изменяется.
+func (p *Platform) InstallPackageManagerProtoc(distro LinuxDistribution, pin string) (bool, error) {
+	manager, err := p.DetectPackageManager(distro)
+	if err != nil {
+		return false, err
+	}
+
+	pkg := manager.ProtocPackage()
+	candidate, err := manager.CandidateVersion(pkg)
+	if err != nil {
+		log.Printf("No %s candidate from %s: %v", pkg, manager.Name(), err)
+		return false, nil
+	}
+	if !VersionSatisfies(ProtocReleaseVersion(candidate), pin) {
+		log.Printf("%s %s from %s does not satisfy the requested version %s", pkg, candidate, manager.Name(), pin)
+		return false, nil
+	}
+
+	var oldVersion string
+	if installed, err := manager.IsInstalled(pkg); err == nil && installed {
+		oldVersion, _ = manager.InstalledVersion(pkg)
+	}
+	if oldVersion == candidate {
+		log.Printf("%s %s is already installed with %s", pkg, candidate, manager.Name())
+		return true, nil
+	}
+
+	log.Printf("Installing %s %s with %s", pkg, candidate, manager.Name())
+	if err := manager.Install(pkg); err != nil {
+		return false, err //nolint:wrapcheck
+	}
+	if err := AppendHistory(HistoryEntry{
+		Tool:       "protoc",
+		OldVersion: oldVersion,
+		NewVersion: candidate,
+		Source:     manager.Name() + ":" + pkg,
+		Target:     HistoryTargetPackageManager,
+	}); err != nil {
+		log.Printf("Failed to record installation history: %v", err)
+	}
+
+	return true, nil
+}
+
+// ProtocReleaseVersion приводит версию пакета или вывода protoc к нумерации релизов protoc на GitHub:
+// начиная с релиза 21.0 библиотека и пакеты дистрибутивов нумеруются 3.21.x, а релизы — 21.x.
+func ProtocReleaseVersion(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) >= 3 && parts[0] == "3" { //nolint:mnd
+		if minor, err := strconv.Atoi(parts[1]); err == nil && minor >= 21 { //nolint:mnd
+			return strings.Join(parts[1:], ".")
+		}
+	}
+
+	return version
+}
+
+// queryDpkg возвращает версию пакета из базы dpkg. Пакеты, от которых остались только конфиги, не считаются установленными.
+func queryDpkg(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("dpkg-query", "-W", "-f=${Status} ${Version}", pkg)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 4 || fields[2] != "installed" { //nolint:mnd
+		return ""
+	}
+
+	return fields[3]
+}
+
+// queryRPM возвращает версию пакета из базы rpm.
+func queryRPM(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("rpm", "-q", "--qf", "%{VERSION}", pkg)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// queryApk возвращает версию пакета из вывода apk list --installed, например protobuf-24.4-r0.
+func queryApk(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("apk", "list", "--installed", pkg)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if version, ok := strings.CutPrefix(fields[0], pkg+"-"); ok && startsWithDigit(version) {
+			return version
+		}
+	}
+
+	return ""
+}
+
+// queryPacman возвращает версию пакета из вывода pacman -Q, например "protobuf 28.3-1".
+func queryPacman(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("pacman", "-Q", pkg)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 || fields[0] != pkg { //nolint:mnd
+		return ""
+	}
+
+	return fields[1]
+}
+
+// candidateApt возвращает версию Candidate из apt-cache policy.
+func candidateApt(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("apt-cache", "policy", pkg)
+	if err != nil {
+		return ""
+	}
+	version := fieldValue(output, "Candidate:")
+	if version == "(none)" {
+		return ""
+	}
+
+	return version
+}
+
+// candidateDnf возвращает наибольшую версию пакета в подключённых репозиториях.
+func candidateDnf(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("dnf", "-q", "repoquery", "--latest-limit=1", "--queryformat=%{version}\n", pkg)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Fields(string(output))
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return lines[len(lines)-1]
+}
+
+// candidateYum возвращает версию из последней строки yum list, где перечислены установленная и доступные версии.
+func candidateYum(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("yum", "-q", "list", pkg)
+	if err != nil {
+		return ""
+	}
+	var version string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && strings.HasPrefix(fields[0], pkg+".") { //nolint:mnd
+			version = fields[1]
+		}
+	}
+
+	return version
+}
+
+// candidateZypper возвращает поле Version из zypper info.
+func candidateZypper(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("zypper", "--non-interactive", "info", pkg)
+	if err != nil {
+		return ""
+	}
+
+	return fieldValue(output, "Version")
+}
+
+// candidateApk возвращает версию из apk search --exact, например protobuf-24.4-r0.
+func candidateApk(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("apk", "search", "--exact", pkg)
+	if err != nil {
+		return ""
+	}
+	for _, field := range strings.Fields(string(output)) {
+		if version, ok := strings.CutPrefix(field, pkg+"-"); ok && startsWithDigit(version) {
+			return version
+		}
+	}
+
+	return ""
+}
+
+// candidatePacman возвращает поле Version из pacman -Si.
+func candidatePacman(p *Platform, pkg string) string {
+	output, err := p.Runner.Output("pacman", "-Si", pkg)
+	if err != nil {
+		return ""
+	}
+
+	return fieldValue(output, "Version")
+}
+
+// removedApt извлекает пакеты из строк "Remv <pkg> [<version>]" вывода apt-get -s remove.
+func removedApt(output []byte) []string {
+	var removed []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "Remv" {
+			removed = append(removed, fields[1])
+		}
+	}
+
+	return removed
+}
+
+// removedDnf извлекает пакеты из таблицы dnf и yum remove --assumeno: строки разделов "Removing...:"
+// до пустой строки. Длинное имя пакета dnf переносит, и остальные колонки оказываются на следующей строке.
+func removedDnf(output []byte) []string {
+	var (
+		removed          []string
+		section, wrapped bool
+	)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "Removing") && strings.HasSuffix(strings.TrimSpace(line), ":"):
+			section, wrapped = true, false
+		case len(fields) == 0 || !strings.HasPrefix(line, " "):
+			section = false
+		case !section:
+		case wrapped:
+			wrapped = false
+		default:
+			removed = append(removed, fields[0])
+			wrapped = len(fields) == 1
+		}
+	}
+
+	return removed
+}
+
+// removedZypper извлекает пакеты из списка "The following N packages are going to be REMOVED:" вывода zypper --dry-run.
+func removedZypper(output []byte) []string {
+	var (
+		removed []string
+		section bool
+	)
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasSuffix(strings.TrimSpace(line), "REMOVED:"):
+			section = true
+		case strings.TrimSpace(line) == "":
+			section = false
+		case section:
+			removed = append(removed, strings.Fields(line)...)
+		}
+	}
+
+	return removed
+}
+
+// removedApk извлекает пакеты из строк "(1/2) Purging <pkg> (<version>)" вывода apk del --simulate.
+func removedApk(output []byte) []string {
+	var removed []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[1] == "Purging" {
+			removed = append(removed, fields[2])
+		}
+	}
+
+	return removed
+}
+
+// removedPacman извлекает пакеты из вывода pacman -R --print --print-format %n, по одному имени в строке.
+func removedPacman(output []byte) []string {
+	return strings.Fields(string(output))
+}
+
+// fieldValue возвращает значение поля вида "Key : value" или "Key: value" из вывода пакетного менеджера.
+func fieldValue(output []byte, key string) string {
+	key = strings.TrimSuffix(key, ":")
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// upstreamVersion отбрасывает epoch и номер сборки пакета: 1:3.21.12-8ubuntu1 -> 3.21.12, 24.4-r0 -> 24.4.
+func upstreamVersion(version string) string {
+	if _, rest, ok := strings.Cut(version, ":"); ok {
+		version = rest
+	}
+	if i := strings.LastIndex(version, "-"); i > 0 {
+		version = version[:i]
+	}
+
+	return version
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func confirmAll(SystemPackage) bool { return true }
+
+func TestPackageManagerInstalledVersion(t *testing.T) {
+	tests := []struct {
+		family  string
+		outputs map[string]string
+		version string
+	}{
+		{
+			family: DistroFamilyDebian,
+			outputs: map[string]string{
+				"dpkg-query -W -f=${Status} ${Version} protobuf-compiler": "install ok installed 3.21.12-8.2ubuntu0.1",
+			},
+			version: "3.21.12",
+		},
+		{
+			family: DistroFamilyRHEL,
+			outputs: map[string]string{
+				"dnf --version": "4.19.0",
+				"rpm -q --qf %{VERSION} protobuf-compiler": "3.19.6",
+			},
+			version: "3.19.6",
+		},
+		{
+			family: DistroFamilyAlpine,
+			outputs: map[string]string{
+				"apk list --installed protoc": "protoc-24.4-r0 x86_64 {protobuf} (BSD-3-Clause) [installed]\n",
+			},
+			version: "24.4",
+		},
+		{
+			family:  DistroFamilyArch,
+			outputs: map[string]string{"pacman -Q protobuf": "protobuf 28.3-1\n"},
+			version: "28.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			p := &Platform{OS: "linux", Arch: "amd64", Runner: &FakeRunner{Outputs: tt.outputs, Strict: true}}
+			manager, err := p.DetectPackageManager(LinuxDistribution{ID: tt.family, Family: tt.family})
+			require.NoError(t, err)
+
+			installed, err := manager.IsInstalled(manager.ProtocPackage())
+			require.NoError(t, err)
+			assert.True(t, installed)
+
+			version, err := manager.InstalledVersion(manager.ProtocPackage())
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func TestPackageManagerDependentPackages(t *testing.T) {
+	dnfOutput := `Dependencies resolved.
+================================================================================
+ Package                    Arch     Version            Repository     Size
+================================================================================
+Removing:
+ protobuf-compiler          x86_64   3.19.6-8.fc40      @fedora       2.6 M
+Removing dependent packages:
+ grpc-plugins               x86_64   1.48.4-32.fc40     @fedora       1.1 M
+ python3-grpcio-tools-with-a-long-name
+                            x86_64   1.48.4-32.fc40     @fedora       0.5 M
+
+Transaction Summary
+================================================================================
+Remove  3 Packages
+
+Operation aborted.
+`
+	tests := []struct {
+		manager    string
+		command    string
+		output     string
+		err        error
+		dependents []string
+	}{
+		{
+			manager: "apt-get",
+			command: "apt-get -s remove protobuf-compiler",
+			output: "Reading package lists...\nThe following packages will be REMOVED:\n  protobuf-compiler protobuf-compiler-grpc\n" +
+				"Remv protobuf-compiler-grpc [1.51.1-4.1build5]\nRemv protobuf-compiler [3.21.12-8.2build1]\n",
+			dependents: []string{"protobuf-compiler-grpc"},
+		},
+		{
+			manager:    "dnf",
+			command:    "sudo dnf remove --assumeno protobuf-compiler",
+			output:     dnfOutput,
+			err:        errors.New("exit status 1"),
+			dependents: []string{"grpc-plugins", "python3-grpcio-tools-with-a-long-name"},
+		},
+		{
+			manager: "zypper",
+			command: "sudo zypper --non-interactive remove --dry-run protobuf",
+			output: "Reading installed packages...\nResolving package dependencies...\n\n" +
+				"The following 2 packages are going to be REMOVED:\n  protobuf-devel protobuf\n\n2 packages to remove.\n",
+			dependents: []string{"protobuf-devel"},
+		},
+		{
+			manager:    "apk",
+			command:    "apk del --simulate protoc",
+			output:     "(1/2) Purging protoc (24.4-r0)\n(2/2) Purging abseil-cpp (20230802.1-r0)\nOK: 12 MiB in 30 packages\n",
+			dependents: []string{"abseil-cpp"},
+		},
+		{
+			manager:    "pacman",
+			command:    "pacman -R --print --print-format %n protobuf",
+			output:     "protobuf\n",
+			dependents: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.manager, func(t *testing.T) {
+			runner := &FakeRunner{Handle: func(command string, args []string) ([]byte, error) {
+				if commandLine(command, args) == tt.command {
+					return []byte(tt.output), tt.err
+				}
+				return nil, nil
+			}}
+			p := &Platform{OS: "linux", Arch: "amd64", Runner: runner, Escalation: EscalationSudo}
+			manager := p.newPackageManager(tt.manager)
+
+			dependents, err := manager.DependentPackages(manager.ProtocPackage())
+			require.NoError(t, err)
+			assert.Equal(t, tt.dependents, dependents)
+			assert.Equal(t, []string{tt.command}, runner.Calls, "Пробное удаление не должно менять систему")
+		})
+	}
+}
+
+func TestRemovePackageManagerProtobufListsDependents(t *testing.T) {
+	runner := &FakeRunner{Strict: true, Outputs: map[string]string{
+		"dpkg-query -W -f=${Status} ${Version} protobuf-compiler": "install ok installed 3.21.12-8",
+		"apt-get -s remove protobuf-compiler":                     "Remv protobuf-compiler-grpc [1.51.1]\nRemv protobuf-compiler [3.21.12-8]\n",
+	}}
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: runner, Escalation: EscalationSudo}
+
+	var confirmed SystemPackage
+	err := p.RemovePackageManagerProtobuf(LinuxDistribution{ID: "debian", Family: DistroFamilyDebian}, func(pkg SystemPackage) bool {
+		confirmed = pkg
+		return false
+	})
+	require.ErrorIs(t, err, ErrRemovalDeclined)
+	assert.Equal(t, []string{"protobuf-compiler-grpc"}, confirmed.Dependents)
+	assert.NotContains(t, runner.Calls, "sudo apt-get remove -y protobuf-compiler")
+}
+
+func TestPackageManagerDpkgRemovedPackage(t *testing.T) {
+	runner := &FakeRunner{Strict: true, Outputs: map[string]string{
+		"dpkg-query -W -f=${Status} ${Version} protobuf-compiler": "deinstall ok config-files 3.21.12-8",
+	}}
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: runner}
+
+	require.NoError(t, p.RemovePackageManagerProtobuf(LinuxDistribution{ID: "debian", Family: DistroFamilyDebian}, confirmAll))
+	assert.NotContains(t, runner.Calls, "sudo apt-get remove -y protobuf-compiler")
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	runner := &FakeRunner{Strict: true, Outputs: map[string]string{
+		"yum --version": "3.4.3",
+		"rpm -q --qf %{VERSION} protobuf-compiler": "2.5.0",
+		"sudo yum remove -y protobuf-compiler":     "",
+	}}
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: runner, Escalation: EscalationSudo}
+
+	distro := LinuxDistribution{ID: "centos", VersionID: "7", Family: DistroFamilyRHEL}
+	manager, err := p.DetectPackageManager(distro)
+	require.NoError(t, err)
+	assert.Equal(t, "yum", manager.Name(), "Без dnf используется yum")
+
+	require.NoError(t, p.RemovePackageManagerProtobuf(distro, confirmAll))
+	assert.Contains(t, runner.Calls, "sudo yum remove -y protobuf-compiler")
+
+	p.Runner = &FakeRunner{Strict: true}
+	_, err = p.DetectPackageManager(LinuxDistribution{ID: "plan9", PrettyName: "Plan 9"})
+	require.ErrorIs(t, err, ErrNoPackageManager)
+	assert.ErrorContains(t, err, "Plan 9")
+
+	fake := &FakePackageManager{ManagerName: "fake", Package: "protoc", Installed: map[string]string{"protoc": "3.12.4"}}
+	p.PackageManager = fake
+	var asked []SystemPackage
+	err = p.RemovePackageManagerProtobuf(LinuxDistribution{ID: "plan9"}, func(pkg SystemPackage) bool {
+		asked = append(asked, pkg)
+		return false
+	})
+	require.ErrorIs(t, err, ErrRemovalDeclined)
+	assert.ErrorContains(t, err, "protoc 3.12.4 (fake)")
+	assert.Len(t, asked, 1)
+	assert.Empty(t, fake.Calls, "Без подтверждения пакет не удаляется")
+
+	require.NoError(t, p.RemovePackageManagerProtobuf(LinuxDistribution{ID: "plan9"}, confirmAll))
+	assert.Equal(t, []string{"remove protoc"}, fake.Calls)
+}
+
+func TestPackageManagerCandidateVersion(t *testing.T) {
+	tests := []struct {
+		family  string
+		outputs map[string]string
+		version string
+	}{
+		{
+			family: DistroFamilyDebian,
+			outputs: map[string]string{
+				"apt-cache policy protobuf-compiler": "protobuf-compiler:\n  Installed: (none)\n  Candidate: 3.21.12-8.2build1\n",
+			},
+			version: "3.21.12",
+		},
+		{
+			family: DistroFamilyRHEL,
+			outputs: map[string]string{
+				"dnf --version": "4.19.0",
+				"dnf -q repoquery --latest-limit=1 --queryformat=%{version}\n protobuf-compiler": "3.19.6\n",
+			},
+			version: "3.19.6",
+		},
+		{
+			family: DistroFamilySUSE,
+			outputs: map[string]string{
+				"zypper --non-interactive info protobuf": "Name           : protobuf\nVersion        : 25.1-2.1\nArch           : x86_64\n",
+			},
+			version: "25.1",
+		},
+		{
+			family:  DistroFamilyAlpine,
+			outputs: map[string]string{"apk search --exact protoc": "protoc-29.2-r0\n"},
+			version: "29.2",
+		},
+		{
+			family:  DistroFamilyArch,
+			outputs: map[string]string{"pacman -Si protobuf": "Repository      : extra\nName            : protobuf\nVersion         : 29.3-1\n"},
+			version: "29.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			p := &Platform{OS: "linux", Arch: "amd64", Runner: &FakeRunner{Outputs: tt.outputs, Strict: true}}
+			manager, err := p.DetectPackageManager(LinuxDistribution{ID: tt.family, Family: tt.family})
+			require.NoError(t, err)
+
+			version, err := manager.CandidateVersion(manager.ProtocPackage())
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+}
+
+func TestInstallPackageManagerProtoc(t *testing.T) {
+	t.Setenv("PROTOCINSTALL_HOME", t.TempDir())
+
+	fake := &FakePackageManager{
+		ManagerName: "apt-get",
+		Package:     "protobuf-compiler",
+		Candidates:  map[string]string{"protobuf-compiler": "3.21.12"},
+	}
+	p := &Platform{OS: "linux", Arch: "amd64", PackageManager: fake}
+	distro := LinuxDistribution{ID: "ubuntu", Family: DistroFamilyDebian}
+
+	installed, err := p.InstallPackageManagerProtoc(distro, "29")
+	require.NoError(t, err)
+	assert.False(t, installed)
+	assert.Empty(t, fake.Calls)
+
+	installed, err = p.InstallPackageManagerProtoc(distro, "21.12")
+	require.NoError(t, err)
+	assert.True(t, installed, "Пакет 3.21.12 соответствует релизу 21.12")
+	assert.Equal(t, []string{"install protobuf-compiler"}, fake.Calls)
+
+	installed, err = p.InstallPackageManagerProtoc(distro, "21")
+	require.NoError(t, err)
+	assert.True(t, installed)
+	assert.Len(t, fake.Calls, 1, "Уже установленный пакет не переустанавливается")
+
+	history, err := ReadHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, HistoryTargetPackageManager, history[0].Target)
+	assert.Equal(t, "3.21.12", history[0].NewVersion)
+}
+
+func TestRemovePackageManagerProtobufDeprecated(t *testing.T) {
+	defer func(runner CommandRunner) { DefaultRunner = runner }(DefaultRunner)
+	t.Setenv("PROTOCINSTALL_ESCALATION", EscalationSudo)
+
+	runner := &FakeRunner{Strict: true, Outputs: map[string]string{
+		"rpm -q --qf %{VERSION} protobuf":                         "3.5.0",
+		"sudo zypper --non-interactive remove protobuf":           "",
+		"sudo zypper --non-interactive remove --dry-run protobuf": "",
+	}}
+	DefaultRunner = runner
+
+	require.NoError(t, RemovePackageManagerProtobuf("suse"))
+	assert.Contains(t, runner.Calls, "sudo zypper --non-interactive remove protobuf",
+		"Устаревшая обёртка удаляет пакет без подтверждения")
+}
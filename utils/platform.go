@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CommandRunner выполняет внешние команды. Все запуски процессов в пакете проходят через него,
+// поэтому в тестах его можно заменить на FakeRunner.
+type CommandRunner interface {
+	// Run запускает команду, перенаправляя её вывод в stdout/stderr процесса.
+	Run(command string, args ...string) error
+	// RunEnv запускает команду как Run, добавляя env (в формате KEY=value) к окружению процесса.
+	RunEnv(env []string, command string, args ...string) error
+	// Output запускает команду и возвращает её стандартный вывод.
+	// Если команда завершилась ошибкой, вместе с ней возвращается вывод, полученный до завершения.
+	Output(command string, args ...string) ([]byte, error)
+}
+
+// DefaultRunner используется HostPlatform и функциями пакета, которые не принимают Platform.
+// SetDefaultContext привязывает его к контексту, после чего такие команды тоже прерываются при отмене.
+var DefaultRunner CommandRunner = ExecRunner{}
+
+// SetDefaultContext заменяет DefaultRunner на ExecRunner с контекстом ctx и таймаутом команд timeout.
+// Влияет на RunCommand, RunCommandWithOutput, обёртки пакета над методами Platform и платформы,
+// созданные HostPlatform после вызова.
+func SetDefaultContext(ctx context.Context, timeout time.Duration) {
+	DefaultRunner = ExecRunner{Context: ctx, Timeout: timeout}
+}
+
+// killGracePeriod время, которое команда получает на завершение после SIGTERM, прежде чем её группа получит SIGKILL.
+const killGracePeriod = 5 * time.Second
+
+// ExecRunner выполняет команды через os/exec.
+// При отмене Context или истечении Timeout команда и запущенные ею процессы получают SIGTERM,
+// а через killGracePeriod — SIGKILL.
+type ExecRunner struct {
+	// Context прерывает запущенные команды при отмене. Пустое значение равносильно context.Background.
+	Context context.Context
+	// Timeout ограничивает время выполнения каждой команды. Ноль отключает ограничение.
+	Timeout time.Duration
+
+	// stdout и stderr получают вывод команд вместо os.Stdout и os.Stderr.
+	stdout, stderr io.Writer
+	// quiet отключает запись команд в лог и обёртку ошибок запуска.
+	quiet bool
+}
+
+// Quiet возвращает копию раннера, который пишет вывод команд в stdout и stderr, не записывает команды в лог
+// и возвращает ошибки запуска без обёртки. Так работают docker- и git-хелперы пакета.
+// Вывод Output в stdout не пишется, он возвращается вызывающему.
+func (r ExecRunner) Quiet(stdout, stderr io.Writer) CommandRunner {
+	r.stdout, r.stderr, r.quiet = stdout, stderr, true
+	return r
+}
+
+// quietRunner возвращает Quiet-копию раннера. Раннеры без метода Quiet, например FakeRunner, возвращаются как есть.
+func quietRunner(runner CommandRunner, stdout, stderr io.Writer) CommandRunner {
+	if r, ok := runner.(interface {
+		Quiet(stdout, stderr io.Writer) CommandRunner
+	}); ok {
+		return r.Quiet(stdout, stderr)
+	}
+
+	return runner
+}
+
+// Run реализует CommandRunner. Перед выполнением команда и её аргументы логируются.
+func (r ExecRunner) Run(command string, args ...string) error {
+	return r.RunEnv(nil, command, args...)
+}
+
+// RunEnv реализует CommandRunner. Перед выполнением команда и её аргументы логируются.
+func (r ExecRunner) RunEnv(env []string, command string, args ...string) error {
+	cmd, ctx, cancel := r.command(command, args)
+	defer cancel()
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = writerOr(r.stdout, os.Stdout)
+	cmd.Stderr = writerOr(r.stderr, os.Stderr)
+
+	if err := cmd.Run(); err != nil {
+		if r.quiet {
+			return r.commandError(ctx, err)
+		}
+		return fmt.Errorf("failed to run command %s with args %v: %w", command, args, r.commandError(ctx, err))
+	}
+
+	return nil
+}
+
+// Output реализует CommandRunner. Перед выполнением команда и её аргументы логируются.
+func (r ExecRunner) Output(command string, args ...string) ([]byte, error) {
+	cmd, ctx, cancel := r.command(command, args)
+	defer cancel()
+	cmd.Stderr = writerOr(r.stderr, os.Stderr)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if r.quiet {
+			return output, r.commandError(ctx, err)
+		}
+		return output, fmt.Errorf("failed to get command output: %w", r.commandError(ctx, err))
+	}
+
+	return output, nil
+}
+
+// command создаёт команду, которая завершается вместе со своей группой процессов при отмене контекста или по таймауту.
+// Возвращаемую функцию нужно вызвать после завершения команды.
+func (r ExecRunner) command(command string, args []string) (*exec.Cmd, context.Context, context.CancelFunc) {
+	if !r.quiet {
+		log.Printf("Running command: %s %s", command, strings.Join(args, " "))
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := context.CancelFunc(func() {})
+	if r.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+	}
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	group := startProcessGroup(cmd)
+	cmd.Cancel = group.terminate
+	cmd.WaitDelay = killGracePeriod
+
+	return cmd, ctx, func() {
+		cancel()
+		group.release()
+	}
+}
+
+func writerOr(w, fallback io.Writer) io.Writer {
+	if w == nil {
+		return fallback
+	}
+
+	return w
+}
+
+// commandError заменяет ошибку прерванной команды причиной прерывания.
+func (r ExecRunner) commandError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s: %w", r.Timeout, context.DeadlineExceeded)
+	case ctx.Err() != nil:
+		return fmt.Errorf("interrupted: %w", ctx.Err())
+	default:
+		return err
+	}
+}
+
+// Detached возвращает копию раннера, команды которой не прерываются отменой Context. Таймаут сохраняется.
+func (r ExecRunner) Detached() CommandRunner {
+	if r.Context != nil {
+		r.Context = context.WithoutCancel(r.Context)
+	}
+
+	return r
+}
+
+// detachedRunner возвращает раннер, команды которого не прерываются отменой контекста.
+// Используется для шагов, которые нельзя оборвать на середине. Раннеры без метода Detached
+// не зависят от контекста и возвращаются как есть.
+func detachedRunner(runner CommandRunner) CommandRunner {
+	if r, ok := runner.(interface{ Detached() CommandRunner }); ok {
+		return r.Detached()
+	}
+
+	return runner
+}
+
+// Platform описывает систему, на которую выполняется установка.
+// Все шаги установщика получают ОС, архитектуру, файловую систему, запуск команд и HTTP через неё,
+// поэтому ветки для darwin и linux можно проверять тестами на любой машине с подменёнными зависимостями.
+type Platform struct {
+	// OS значение в формате runtime.GOOS.
+	OS string
+	// Arch значение в формате runtime.GOARCH.
+	Arch string
+	// Root корень файловой системы, относительно которого разрешаются системные пути вроде /etc и /usr/local.
+	Root string
+	// Runner выполняет внешние команды.
+	Runner CommandRunner
+	// HTTP клиент для запросов к GitHub и Homebrew.
+	HTTP *http.Client
+	// PackageManager системный пакетный менеджер. Если не задан, определяется по дистрибутиву.
+	PackageManager PackageManager
+	// Context прерывает HTTP-запросы при отмене. Пустое значение равносильно context.Background.
+	Context context.Context
+	// Escalation способ повышения прав: EscalationAuto, EscalationNone, EscalationSudo или EscalationDoas.
+	// Пустое значение равносильно EscalationAuto.
+	Escalation string
+
+	// euid подменяет os.Geteuid в тестах.
+	euid func() int
+}
+
+// HostPlatform возвращает описание текущей системы.
+func HostPlatform() *Platform {
+	return &Platform{
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Root:   "/",
+		Runner: DefaultRunner,
+		HTTP:   http.DefaultClient,
+		// Способ повышения прав можно задать заранее, например в Dockerfile
+		Escalation: os.Getenv("PROTOCINSTALL_ESCALATION"),
+	}
+}
+
+// SetContext привязывает HTTP-запросы и команды платформы к ctx. Если команды выполняет ExecRunner,
+// каждая из них дополнительно ограничивается timeout; ноль отключает ограничение.
+func (p *Platform) SetContext(ctx context.Context, timeout time.Duration) {
+	p.Context = ctx
+	if runner, ok := p.Runner.(ExecRunner); ok {
+		runner.Context = ctx
+		runner.Timeout = timeout
+		p.Runner = runner
+	}
+}
+
+func (p *Platform) context() context.Context {
+	if p.Context == nil {
+		return context.Background()
+	}
+
+	return p.Context
+}
+
+// Path возвращает системный путь name относительно корня платформы.
+func (p *Platform) Path(name string) string {
+	return filepath.Join(p.Root, name)
+}
+
+// fetch выполняет GET-запрос и возвращает тело ответа.
+func (p *Platform) fetch(url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(p.context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, nil
+}
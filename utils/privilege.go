@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Способы повышения прав для команд, которые пишут в системные каталоги или вызывают пакетный менеджер.
+const (
+	// EscalationAuto не повышает права, если процесс запущен от root или цель доступна на запись,
+	// иначе использует sudo или doas, смотря что есть в PATH.
+	EscalationAuto = "auto"
+	// EscalationNone запускает команды как есть.
+	EscalationNone = "none"
+	// EscalationSudo запускает команды через sudo.
+	EscalationSudo = "sudo"
+	// EscalationDoas запускает команды через doas.
+	EscalationDoas = "doas"
+)
+
+// ErrNoEscalation возвращается, если нужны права root, а ни sudo, ни doas не найдены.
+var ErrNoEscalation = errors.New("root privileges are required but neither sudo nor doas is available")
+
+// EscalationModes перечисляет допустимые значения Platform.Escalation.
+var EscalationModes = []string{EscalationAuto, EscalationNone, EscalationSudo, EscalationDoas}
+
+// privilegedRunner возвращает функцию запуска команд, которым нужна запись в paths.
+// Пустой список paths означает, что команде нужны права root независимо от каталогов, как пакетному менеджеру.
+func (p *Platform) privilegedRunner(paths ...string) (func(command string, args ...string) error, error) {
+	tool, err := p.escalationTool(paths)
+	if err != nil {
+		return nil, err
+	}
+	if tool == "" {
+		return p.Runner.Run, nil
+	}
+
+	return func(command string, args ...string) error {
+		return p.Runner.Run(tool, append([]string{command}, args...)...)
+	}, nil
+}
+
+// privilegedOutput как privilegedRunner, но возвращает функцию, которая возвращает вывод команды.
+func (p *Platform) privilegedOutput(paths ...string) (func(command string, args ...string) ([]byte, error), error) {
+	tool, err := p.escalationTool(paths)
+	if err != nil {
+		return nil, err
+	}
+	if tool == "" {
+		return p.Runner.Output, nil
+	}
+
+	return func(command string, args ...string) ([]byte, error) {
+		return p.Runner.Output(tool, append([]string{command}, args...)...)
+	}, nil
+}
+
+// escalationTool возвращает команду повышения прав или пустую строку, если она не нужна.
+func (p *Platform) escalationTool(paths []string) (string, error) {
+	switch p.Escalation {
+	case EscalationNone:
+		return "", nil
+	case EscalationSudo, EscalationDoas:
+		return p.Escalation, nil
+	case "", EscalationAuto:
+	default:
+		return "", fmt.Errorf("unknown privilege escalation %q, expected one of %v", p.Escalation, EscalationModes)
+	}
+
+	if p.isRoot() || (len(paths) > 0 && allWritable(paths)) {
+		return "", nil
+	}
+	for _, tool := range []string{EscalationSudo, EscalationDoas} {
+		if len(LookPathAll(tool)) > 0 {
+			return tool, nil
+		}
+	}
+
+	return "", ErrNoEscalation
+}
+
+func (p *Platform) isRoot() bool {
+	if p.euid != nil {
+		return p.euid() == 0
+	}
+
+	return os.Geteuid() == 0
+}
+
+// allWritable сообщает, может ли текущий пользователь создавать файлы во всех каталогах paths.
+// Несуществующие каталоги проверяются по ближайшему существующему родителю.
+func allWritable(paths []string) bool {
+	for _, path := range paths {
+		for {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(path)
+			if parent == path {
+				return false
+			}
+			path = parent
+		}
+
+		file, err := os.CreateTemp(path, ".protocinstall-write-*")
+		if err != nil {
+			return false
+		}
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	return true
+}
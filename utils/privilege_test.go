@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalationTool(t *testing.T) {
+	tools := t.TempDir()
+	t.Setenv("PATH", tools)
+	user := func() int { return 1000 }
+	root := func() int { return 0 }
+
+	p := &Platform{OS: "linux", Arch: "amd64", euid: user}
+	_, err := p.escalationTool(nil)
+	require.ErrorIs(t, err, ErrNoEscalation)
+
+	writeExecutable(t, filepath.Join(tools, "doas"))
+	tool, err := p.escalationTool(nil)
+	require.NoError(t, err)
+	assert.Equal(t, EscalationDoas, tool)
+
+	writeExecutable(t, filepath.Join(tools, "sudo"))
+	tool, err = p.escalationTool(nil)
+	require.NoError(t, err)
+	assert.Equal(t, EscalationSudo, tool, "sudo предпочтительнее doas")
+
+	tool, err = p.escalationTool([]string{filepath.Join(t.TempDir(), "usr", "local")})
+	require.NoError(t, err)
+	assert.Empty(t, tool, "В доступный на запись каталог пишем без повышения прав")
+
+	p.euid = root
+	tool, err = p.escalationTool(nil)
+	require.NoError(t, err)
+	assert.Empty(t, tool, "От root команды запускаются как есть")
+
+	p.Escalation = EscalationDoas
+	tool, err = p.escalationTool(nil)
+	require.NoError(t, err)
+	assert.Equal(t, EscalationDoas, tool, "Явно заданный способ используется всегда")
+
+	p.Escalation = EscalationNone
+	p.euid = user
+	tool, err = p.escalationTool(nil)
+	require.NoError(t, err)
+	assert.Empty(t, tool)
+
+	p.Escalation = "su"
+	_, err = p.escalationTool(nil)
+	assert.ErrorContains(t, err, `unknown privilege escalation "su"`)
+}
+
+func TestPrivilegedRunner(t *testing.T) {
+	runner := &FakeRunner{}
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: runner, Escalation: EscalationDoas}
+
+	run, err := p.privilegedRunner()
+	require.NoError(t, err)
+	require.NoError(t, run("apk", "del", "protobuf"))
+
+	p.Escalation = EscalationNone
+	run, err = p.privilegedRunner()
+	require.NoError(t, err)
+	require.NoError(t, run("apk", "del", "protobuf"))
+
+	assert.Equal(t, []string{"doas apk del protobuf", "apk del protobuf"}, runner.Calls)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smokeRunner возвращает FakeRunner, который сохраняет аргументы protoc и импорты smoke.proto.
+// При writesOutput запуск protoc записывает набор дескрипторов.
+func smokeRunner(writesOutput bool, args *[]string, imported *string) *FakeRunner {
+	return &FakeRunner{Handle: func(_ string, protocArgs []string) ([]byte, error) {
+		*args = protocArgs
+		content, err := os.ReadFile(filepath.Join(protocArgs[1], "smoke.proto"))
+		if err != nil {
+			return nil, err
+		}
+		*imported = string(content)
+		for _, arg := range protocArgs {
+			if out, ok := strings.CutPrefix(arg, "--descriptor_set_out="); ok && writesOutput {
+				return nil, os.WriteFile(out, []byte("descriptor"), 0o644)
+			}
+		}
+
+		return nil, nil
+	}}
+}
+
+func TestSmokeTestProtoc(t *testing.T) {
+	plugins := t.TempDir()
+	writeExecutable(t, filepath.Join(plugins, "protoc-gen-go"))
+	t.Setenv("PATH", plugins)
+
+	var args []string
+	var imported string
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: smokeRunner(true, &args, &imported)}
+	require.NoError(t, p.SmokeTestProtoc("/opt/protoc/bin/protoc", "/opt/protoc/include"))
+
+	assert.Contains(t, imported, `import "google/protobuf/timestamp.proto";`)
+	assert.Contains(t, args, "/opt/protoc/include")
+	assert.Contains(t, args, "--plugin=protoc-gen-go="+filepath.Join(plugins, "protoc-gen-go"))
+	for _, arg := range args {
+		assert.NotContains(t, arg, "protoc-gen-go-grpc", "Отсутствующий плагин не должен запускаться")
+	}
+
+	// Сломанный плагин не должен проваливать установку protoc
+	writeExecutable(t, filepath.Join(plugins, "protoc-gen-go-grpc"))
+	runner := smokeRunner(true, &args, &imported)
+	handle := runner.Handle
+	runner.Handle = func(command string, protocArgs []string) ([]byte, error) {
+		for _, arg := range protocArgs {
+			if strings.HasPrefix(arg, "--plugin=protoc-gen-go-grpc=") {
+				return nil, errors.New("protoc-gen-go-grpc: program not found or is not executable")
+			}
+		}
+		return handle(command, protocArgs)
+	}
+	p.Runner = runner
+	require.NoError(t, p.SmokeTestProtoc("/opt/protoc/bin/protoc", "/opt/protoc/include"))
+	assert.Len(t, runner.Calls, 3, "Каждый плагин проверяется отдельным запуском protoc")
+
+	p.Runner = smokeRunner(false, &args, &imported)
+	err := p.SmokeTestProtoc("/opt/protoc/bin/protoc", "/opt/protoc/include")
+	assert.ErrorContains(t, err, "did not write a descriptor set")
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrReleaseChecksumMismatch возвращается, если скачанный архив не совпадает с контрольной суммой, опубликованной в релизе.
+var ErrReleaseChecksumMismatch = errors.New("downloaded archive does not match the release checksum")
+
+// Tool описывает инструмент, релизные архивы которого публикуются на GitHub.
+type Tool struct {
+	// Name имя инструмента, используется как ключ в кэше загрузок.
+	Name string
+	// Repo репозиторий GitHub в виде owner/name.
+	Repo string
+	// Tag возвращает тег релиза для версии.
+	Tag func(version string) string
+	// Assets возвращает возможные имена архивов для версии, ОС и архитектуры в порядке предпочтения.
+	Assets func(version, goos, goarch string) ([]string, error)
+	// ResolveAsset уточняет имя архива по опубликованному релизу. Может быть nil.
+	ResolveAsset func(p *Platform, version, goos, goarch string) (string, error)
+	// LatestVersion возвращает последнюю версию инструмента. Может быть nil.
+	LatestVersion func(p *Platform) (string, error)
+}
+
+// Tools реестр инструментов, которые умеет скачивать protocInstall.
+var Tools = map[string]Tool{
+	"protoc": {
+		Name:          "protoc",
+		Repo:          "protocolbuffers/protobuf",
+		Tag:           func(version string) string { return "v" + version },
+		Assets:        protocAssetCandidates,
+		ResolveAsset:  (*Platform).ResolveProtocAsset,
+		LatestVersion: (*Platform).checkGitHubProtobufVersion,
+	},
+	"protoc-gen-go": {
+		Name:   "protoc-gen-go",
+		Repo:   "protocolbuffers/protobuf-go",
+		Tag:    func(version string) string { return "v" + version },
+		Assets: goPluginAssetCandidates("protoc-gen-go"),
+		LatestVersion: func(p *Platform) (string, error) {
+			return p.checkGitHubLatestRelease("protocolbuffers/protobuf-go")
+		},
+	},
+	"protoc-gen-go-grpc": {
+		Name:   "protoc-gen-go-grpc",
+		Repo:   "grpc/grpc-go",
+		Tag:    func(version string) string { return "cmd/protoc-gen-go-grpc/v" + version },
+		Assets: goPluginAssetCandidates("protoc-gen-go-grpc"),
+	},
+}
+
+// LookupTool возвращает инструмент из реестра по имени.
+func LookupTool(name string) (Tool, error) {
+	tool, ok := Tools[name]
+	if !ok {
+		names := make([]string, 0, len(Tools))
+		for toolName := range Tools {
+			names = append(names, toolName)
+		}
+		sort.Strings(names)
+		return Tool{}, fmt.Errorf("unknown tool %s, available: %s", name, strings.Join(names, ", "))
+	}
+
+	return tool, nil
+}
+
+// ReleaseURL возвращает адрес архива asset в релизе инструмента указанной версии.
+func (t Tool) ReleaseURL(version, asset string) string {
+	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", t.Repo, url.PathEscape(t.Tag(version)), asset)
+}
+
+// goPluginAssetCandidates возвращает функцию именования архивов плагинов на Go,
+// которые публикуются как <name>.v<version>.<goos>.<goarch>.tar.gz (zip для Windows).
+func goPluginAssetCandidates(name string) func(version, goos, goarch string) ([]string, error) {
+	return func(version, goos, goarch string) ([]string, error) {
+		extension := "tar.gz"
+		if goos == "windows" {
+			extension = "zip"
+		}
+
+		return []string{fmt.Sprintf("%s.v%s.%s.%s.%s", name, version, goos, goarch, extension)}, nil
+	}
+}
+
+// FetchToolArchive возвращает путь к архиву инструмента в кэше загрузок, скачивая его при отсутствии.
+// Кэш проверяется до обращения к сети, поэтому уже скачанные версии доступны офлайн.
+func (p *Platform) FetchToolArchive(tool Tool, version, goos, goarch string) (string, error) {
+	candidates, err := tool.Assets(version, goos, goarch)
+	if err != nil {
+		return "", err
+	}
+	if archive, ok := lookupCachedToolArchive(tool, version, goos, goarch); ok {
+		return archive, nil
+	}
+
+	asset := candidates[0]
+	if tool.ResolveAsset != nil {
+		if asset, err = tool.ResolveAsset(p, version, goos, goarch); err != nil {
+			return "", err
+		}
+	}
+
+	return FetchCachedArchive(tool.Name, version, goos, goarch, asset, func(dest string) error {
+		if err := p.DownloadFile(tool.ReleaseURL(version, asset), dest); err != nil {
+			return err
+		}
+
+		return p.verifyReleaseChecksum(tool, version, asset, dest)
+	})
+}
+
+// verifyReleaseChecksum сверяет скачанный архив с контрольной суммой, которую GitHub публикует для архивов релиза.
+// Если релиз её не содержит или недоступен, архив принимается: файл .sha256 в кэше тогда защищает
+// только от порчи уже скачанного архива, но не подтверждает, что он совпадает с опубликованным.
+func (p *Platform) verifyReleaseChecksum(tool Tool, version, asset, archive string) error {
+	assets, err := p.fetchReleaseAssets(tool.Repo, tool.Tag(version))
+	if err != nil {
+		log.Printf("Failed to get the release checksum of %s, it is verified against the local cache only: %v", asset, err)
+		return nil
+	}
+
+	for _, published := range assets {
+		if published.Name != asset {
+			continue
+		}
+		expected, ok := strings.CutPrefix(published.Digest, "sha256:")
+		if !ok {
+			break
+		}
+		sum, err := fileSHA256(archive)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, expected) {
+			return fmt.Errorf("%w: %s has sha256 %s, the release publishes %s", ErrReleaseChecksumMismatch, asset, sum, expected)
+		}
+		log.Printf("Verified %s against the release checksum", asset)
+
+		return nil
+	}
+	log.Printf("The release publishes no checksum for %s, it is verified against the local cache only", asset)
+
+	return nil
+}
+
+// lookupCachedToolArchive ищет архив инструмента в кэше под всеми известными именами без обращения к сети.
+func lookupCachedToolArchive(tool Tool, version, goos, goarch string) (string, bool) {
+	candidates, err := tool.Assets(version, goos, goarch)
+	if err != nil {
+		return "", false
+	}
+	for _, candidate := range candidates {
+		if archive, err := LookupCachedArchive(tool.Name, version, goos, goarch, candidate); err == nil {
+			return archive, true
+		}
+	}
+
+	return "", false
+}
+
+// DownloadTool скачивает архив инструмента для указанных ОС и архитектуры и распаковывает его в outputDir.
+// Инструмент не устанавливается в систему и не запускается, поэтому можно готовить архивы для другой платформы.
+func (p *Platform) DownloadTool(tool Tool, version, goos, goarch, outputDir string) error {
+	archive, err := p.FetchToolArchive(tool, version, goos, goarch)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return p.ExtractArchive(archive, outputDir)
+}
+
+// DownloadFile скачивает файл по url в dest.
+func (p *Platform) DownloadFile(url, dest string) error {
+	if _, err := p.Runner.Output("curl", "-fL", "-o", dest, url); err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// ExtractArchive распаковывает zip или tar.gz архив в каталог dir.
+func (p *Platform) ExtractArchive(archive, dir string) error {
+	var err error
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		err = p.Runner.Run("unzip", "-q", "-o", archive, "-d", dir)
+	case strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
+		err = p.Runner.Run("tar", "-xzf", archive, "-C", dir)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", archive)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", archive, err)
+	}
+
+	return nil
+}
+
+// ParseTarget приводит ОС и архитектуру из командной строки к значениям GOOS и GOARCH.
+// Помимо GOOS/GOARCH принимаются имена из релизов protoc: osx, win64, win32, x86_64, aarch_64 и т.д.
+func ParseTarget(goos, goarch string) (string, string, error) {
+	switch strings.ToLower(goos) {
+	case "linux":
+		goos = "linux"
+	case "darwin", "osx", "macos":
+		goos = "darwin"
+	case "windows":
+		goos = "windows"
+	case "win64":
+		return "windows", "amd64", nil
+	case "win32":
+		return "windows", "386", nil
+	default:
+		return "", "", fmt.Errorf("unsupported target OS: %s", goos)
+	}
+
+	switch strings.ToLower(goarch) {
+	case "x86_64", "x86-64", "x64":
+		goarch = "amd64"
+	case "aarch_64", "aarch64":
+		goarch = "arm64"
+	case "x86_32", "i386", "i686":
+		goarch = "386"
+	case "ppcle_64", "ppc_64":
+		goarch = "ppc64le"
+	case "s390_64":
+		goarch = "s390x"
+	case "universal_binary":
+		goarch = "universal"
+	default:
+		goarch = strings.ToLower(goarch)
+	}
+
+	return goos, goarch, nil
+}
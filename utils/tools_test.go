@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		os, arch     string
+		goos, goarch string
+		expectError  bool
+	}{
+		{"linux", "aarch_64", "linux", "arm64", false},
+		{"osx", "universal_binary", "darwin", "universal", false},
+		{"win64", "arm64", "windows", "amd64", false},
+		{"darwin", "x86_64", "darwin", "amd64", false},
+		{"plan9", "amd64", "", "", true},
+	}
+
+	for _, tt := range tests {
+		goos, goarch, err := ParseTarget(tt.os, tt.arch)
+		if tt.expectError {
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.goos, goos)
+		assert.Equal(t, tt.goarch, goarch)
+	}
+}
+
+func TestToolAssets(t *testing.T) {
+	assets, err := Tools["protoc"].Assets("29.3", "windows", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"protoc-29.3-win64.zip"}, assets)
+
+	assets, err = Tools["protoc-gen-go-grpc"].Assets("1.5.1", "linux", "arm64")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"protoc-gen-go-grpc.v1.5.1.linux.arm64.tar.gz"}, assets)
+
+	assert.Equal(t,
+		"https://github.com/grpc/grpc-go/releases/download/cmd%2Fprotoc-gen-go-grpc%2Fv1.5.1/protoc-gen-go-grpc.v1.5.1.linux.arm64.tar.gz",
+		Tools["protoc-gen-go-grpc"].ReleaseURL("1.5.1", assets[0]))
+}
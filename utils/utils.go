@@ -2,18 +2,18 @@ package utils
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"log"
 	"math/rand"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -46,6 +46,11 @@ func GetModuleName(dir string) (string, error) {
 
 // RunDockerCompose запускает docker-compose с указанными аргументами.
 func RunDockerCompose(envFiles []string, composeFile string, args ...string) error {
+	return HostPlatform().RunDockerCompose(envFiles, composeFile, args...)
+}
+
+// RunDockerCompose запускает docker-compose с указанными аргументами.
+func (p *Platform) RunDockerCompose(envFiles []string, composeFile string, args ...string) error {
 	// Предварительно выделяем память для cmdArgs, исходя из количества env-файлов и других аргументов
 	cmdArgs := make([]string, 0, len(envFiles)*2+len(args)+3) //nolint:mnd // len(envFiles)*2 для --env-file и его значения, +2 для -f и composeFile
 	cmdArgs = append(cmdArgs, "compose")
@@ -61,64 +66,44 @@ func RunDockerCompose(envFiles []string, composeFile string, args ...string) err
 	// Добавляем оставшиеся аргументы
 	cmdArgs = append(cmdArgs, args...)
 
-	// Создаем команду для запуска docker-compose
-	cmd := exec.Command("docker", cmdArgs...)
-
-	// Запускаем команду и проверяем ошибки
-	cmd.Stdout = log.Writer()
-	cmd.Stderr = log.Writer()
-	err := cmd.Run()
-	if err != nil {
-		return err //nolint:wrapcheck
-	}
-
-	return nil
+	// Вывод docker compose, как и раньше, идёт в лог
+	return quietRunner(p.Runner, log.Writer(), log.Writer()).Run("docker", cmdArgs...) //nolint:wrapcheck
 }
 
-// RunDockerComposeWithCustomEnv запускает docker-compose один сервис с env переменными из map[string]string.
+// RunDockerComposeOneService запускает docker-compose один сервис с env переменными из map[string]string.
 func RunDockerComposeOneService(env map[string]string, composeFile string, serviceName string) error {
-	// Pre-allocate memory for cmdArgs, considering the compose file and other arguments
-	cmdArgs := []string{"compose", "-f", composeFile, "up", serviceName, "-d", "--build"}
+	return HostPlatform().RunDockerComposeOneService(env, composeFile, serviceName)
+}
 
-	// Create the environment slice to pass to the command
-	envVars := os.Environ() // Get current environment variables
-	for key, value := range env {
-		envVars = append(envVars, fmt.Sprintf("%s=%s", key, value))
+// RunDockerComposeOneService запускает docker-compose один сервис с env переменными из map[string]string.
+// Переменные добавляются к окружению процесса в порядке сортировки ключей.
+func (p *Platform) RunDockerComposeOneService(env map[string]string, composeFile string, serviceName string) error {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	// Create the command to run docker-compose
-	cmd := exec.Command("docker", cmdArgs...)
-	cmd.Env = envVars // Pass the environment variables
-
-	// Attach the command's output to logs
-	cmd.Stdout = log.Writer()
-	cmd.Stderr = log.Writer()
-
-	// Run the command and handle errors
-	err := cmd.Run()
-	if err != nil {
-		return err // nolint:wrapcheck
+	envVars := make([]string, 0, len(keys))
+	for _, key := range keys {
+		envVars = append(envVars, fmt.Sprintf("%s=%s", key, env[key]))
 	}
 
-	return nil
+	return quietRunner(p.Runner, log.Writer(), log.Writer()).RunEnv(envVars, "docker", "compose", "-f", composeFile, "up", serviceName, "-d", "--build") //nolint:wrapcheck
 }
 
 // StopDockerComposeOneService останавливает docker-compose один сервис.
 func StopDockerComposeOneService(serviceName string) {
-	// Создаем команду для остановки docker-compose
-	cmd := exec.Command("docker", "rm", "-f", serviceName)
-
-	// Запускаем команду и проверяем ошибки
-	cmd.Stdout = log.Writer()
-	cmd.Stderr = log.Writer()
-	err := cmd.Run()
-	if err != nil {
+	if err := HostPlatform().StopDockerComposeOneService(serviceName); err != nil {
 		log.Fatalf("Failed to stop docker-compose: %v", err)
-
-		return
 	}
 }
 
+// StopDockerComposeOneService останавливает docker-compose один сервис.
+func (p *Platform) StopDockerComposeOneService(serviceName string) error {
+	return quietRunner(p.Runner, log.Writer(), log.Writer()).Run("docker", "rm", "-f", serviceName) //nolint:wrapcheck
+}
+
 // GenerateUniquePort генерирует уникальный порт.
 func GenerateUniquePort() int {
 	rand.Seed(time.Now().UnixNano()) //nolint:staticcheck
@@ -169,26 +154,23 @@ func GetSchemaName(schemaRef *openapi3.SchemaRef) string {
 
 // IsAlpine проверяет, работает ли система на базе дистрибутива Alpine Linux.
 func IsAlpine() bool {
-	output, err := exec.Command("cat", "/etc/os-release").Output()
-	if err != nil {
-		return false
-	}
-	return strings.Contains(string(output), "Alpine")
+	return HostPlatform().IsAlpine()
 }
 
 // GetGitBranchName returns the current Git branch name.
 func GetGitBranchName() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+	return HostPlatform().GetGitBranchName()
+}
+
+// GetGitBranchName returns the current Git branch name.
+func (p *Platform) GetGitBranchName() (string, error) {
+	out, err := quietRunner(p.Runner, nil, io.Discard).Output("git", "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
-		return "unknown", err
+		return "unknown", err //nolint:wrapcheck
 	}
 
 	// Trim whitespace characters like newline
-	branchName := strings.TrimSpace(out.String())
-	return branchName, nil
+	return strings.TrimSpace(string(out)), nil
 }
 
 func GetFieldPackageMapFromFile(filePath string, structName string) (map[string]string, error) {
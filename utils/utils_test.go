@@ -1,10 +1,16 @@
 package utils
 
 import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Unit тест для функции GetLastSegment.
@@ -91,3 +97,87 @@ func TestGetSchemaName_EndsWithSlash(t *testing.T) {
 	schemaName := GetSchemaName(schemaRef)
 	assert.Equal(t, "", schemaName, "Функция должна вернуть пустую строку, если Ref заканчивается на '/'")
 }
+
+// Unit тест для docker и git через FakeRunner.
+func TestDockerAndGitCommands(t *testing.T) {
+	runner := &FakeRunner{
+		Outputs: map[string]string{
+			"docker ps --format {{.Names}}":   "postgres\nredis\n",
+			"git rev-parse --abbrev-ref HEAD": "feature/runner\n",
+		},
+		Errors: map[string]error{"docker rm -f kafka": errors.New("exit status 1")},
+	}
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: runner}
+
+	running, err := p.IsContainerRunning("redis")
+	require.NoError(t, err)
+	assert.True(t, running, "Контейнер из вывода docker ps должен считаться запущенным")
+	running, err = p.IsContainerRunning("kafka")
+	require.NoError(t, err)
+	assert.False(t, running)
+
+	branch, err := p.GetGitBranchName()
+	require.NoError(t, err)
+	assert.Equal(t, "feature/runner", branch)
+
+	require.NoError(t, p.RunDockerCompose([]string{".env"}, "compose.yml", "up", "-d"))
+	require.NoError(t, p.RunDockerComposeOneService(map[string]string{"PORT": "8080", "DB": "test"}, "compose.yml", "api"))
+	require.NoError(t, p.StopDockerComposeOneService("api"))
+	assert.Error(t, p.StopDockerComposeOneService("kafka"))
+
+	assert.Contains(t, runner.Calls, "docker compose --env-file .env -f compose.yml up -d")
+	assert.Equal(t, []string{"DB=test", "PORT=8080"}, runner.Env["docker compose -f compose.yml up api -d --build"],
+		"Переменные окружения должны передаваться в порядке сортировки ключей")
+	assert.Contains(t, runner.Calls, "docker rm -f api")
+
+	p.Runner = &FakeRunner{Strict: true}
+	branch, err = p.GetGitBranchName()
+	require.ErrorIs(t, err, ErrUnscriptedCommand)
+	assert.Equal(t, "unknown", branch)
+}
+
+// Unit тест: docker- и git-хелперы с настоящим ExecRunner пишут вывод docker в лог, не логируют
+// команды и возвращают ошибки запуска без обёртки, как до выделения CommandRunner.
+func TestDockerAndGitCommandsOutput(t *testing.T) {
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte(`#!/bin/sh
+case "$1" in
+ps) echo postgres ;;
+rm) echo "removed $3"; echo "warning: $3" >&2; [ "$3" = api ] || exit 3 ;;
+*) echo "compose $*"; echo "compose PORT=$PORT" >&2 ;;
+esac
+`), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\necho main\necho noise >&2\n"), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: ExecRunner{}}
+	require.NoError(t, p.RunDockerCompose(nil, "compose.yml", "up"))
+	require.NoError(t, p.RunDockerComposeOneService(map[string]string{"PORT": "8080"}, "compose.yml", "api"))
+	require.NoError(t, p.StopDockerComposeOneService("api"))
+	err := p.StopDockerComposeOneService("kafka")
+	require.Error(t, err)
+	assert.Equal(t, "exit status 3", err.Error(), "Ошибка docker возвращается без обёртки")
+
+	running, err := p.IsContainerRunning("postgres")
+	require.NoError(t, err)
+	assert.True(t, running)
+	branch, err := p.GetGitBranchName()
+	require.NoError(t, err)
+	assert.Equal(t, "main", branch)
+
+	output := logs.String()
+	assert.Contains(t, output, "compose compose -f compose.yml up")
+	assert.Contains(t, output, "compose PORT=8080", "Переменные окружения передаются docker compose")
+	assert.Contains(t, output, "removed api")
+	assert.Contains(t, output, "warning: kafka", "stderr docker тоже пишется в лог")
+	assert.NotContains(t, output, "Running command", "Хелперы не логируют команды")
+	assert.NotContains(t, output, "noise", "stderr git не выводится")
+
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nexit 1\n"), 0o755))
+	_, err = p.IsContainerRunning("postgres")
+	assert.EqualError(t, err, "ошибка при выполнении docker ps: exit status 1")
+}
@@ -1,21 +1,22 @@
 package utils
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
 	"os"
+	"path/filepath"
 	"regexp"
-	"runtime"
 	"runtime/debug"
 	"strconv"
 	"strings"
 )
 
 type BrewProtocVersion struct {
-	Version BrewProtocStable `json:"versions"`
+	Name             string           `json:"name"`
+	Version          BrewProtocStable `json:"versions"`
+	VersionedFormula []string         `json:"versioned_formulae"` //nolint:tagliatelle
 }
 
 type BrewProtocStable struct {
@@ -100,50 +101,50 @@ func BumpVersion(version, bumpType string) (string, error) {
 	return fmt.Sprintf("%d.%d.%d", major, minor, patch), nil
 }
 
+// GetStableProtocVersion возвращает последнюю стабильную версию protoc для текущей системы.
+//
+// Deprecated: используйте Platform.GetStableProtocVersion.
 func GetStableProtocVersion() (string, error) {
-	platform := runtime.GOOS
-	switch platform {
+	return HostPlatform().GetStableProtocVersion()
+}
+
+// GetStableProtocVersion возвращает последнюю стабильную версию protoc для платформы:
+// для darwin из формулы Homebrew, для linux из релизов GitHub.
+func (p *Platform) GetStableProtocVersion() (string, error) {
+	switch p.OS {
 	case "darwin":
-		resp, err := http.Get("https://formulae.brew.sh/api/formula/protobuf.json")
+		brewProtocVersion, err := p.fetchBrewFormula("protobuf")
 		if err != nil {
 			return "", fmt.Errorf("failed to get stable protoc version: %w", err)
 		}
-		defer resp.Body.Close()
-
-		// Read the response body
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Printf("Error reading response body: %v\n", err)
-			return "", err
-		}
-		var brewProtocVersion BrewProtocVersion
-		if err := json.Unmarshal(body, &brewProtocVersion); err != nil {
-			return "", fmt.Errorf("failed to unmarshal protoc version: %w", err)
-		}
 
 		return brewProtocVersion.Version.Stable, nil
 	case "linux":
-		protocVersion, err := checkGitHubProtobufVersion()
+		protocVersion, err := p.checkGitHubProtobufVersion()
 		if err != nil {
 			return "", fmt.Errorf("failed to check github protobuf version: %w", err)
 		}
 
 		return protocVersion, nil
 	default:
-		return "", fmt.Errorf("не поддерживается платформа: %s", platform)
+		return "", fmt.Errorf("не поддерживается платформа: %s", p.OS)
 	}
 }
 
-func checkGitHubProtobufVersion() (string, error) {
-	resp, err := http.Get("https://api.github.com/repos/protocolbuffers/protobuf/releases/latest")
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch latest protobuf release: %w", err)
-	}
-	defer resp.Body.Close()
+// GetGithubProtocVersion возвращает версию последнего релиза protobuf на GitHub независимо от ОС.
+func (p *Platform) GetGithubProtocVersion() (string, error) {
+	return p.checkGitHubProtobufVersion()
+}
+
+func (p *Platform) checkGitHubProtobufVersion() (string, error) {
+	return p.checkGitHubLatestRelease("protocolbuffers/protobuf")
+}
 
-	body, err := io.ReadAll(resp.Body)
+// checkGitHubLatestRelease возвращает версию последнего релиза репозитория GitHub без префикса v.
+func (p *Platform) checkGitHubLatestRelease(repo string) (string, error) {
+	body, err := p.fetch(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return "", fmt.Errorf("failed to fetch latest %s release: %w", repo, err)
 	}
 
 	var release struct {
@@ -157,109 +158,188 @@ func checkGitHubProtobufVersion() (string, error) {
 	return version, nil
 }
 
+// protocManagedPaths перечисляет пути внутри префикса установки, которые принадлежат архиву protoc.
+var protocManagedPaths = []string{"bin/protoc", "include/google/protobuf"}
+
+// InstallProtoBufLinuxGithub устанавливает protoc из релиза GitHub в /usr/local текущей системы.
+//
+// Deprecated: используйте Platform.InstallProtoBufGithub.
 func InstallProtoBufLinuxGithub(version string) error {
-	var architecture string
-	url := `https://github.com/protocolbuffers/protobuf/releases/download/v%s/protoc-%s-linux-%s.zip`
-	switch runtime.GOARCH {
-	case "amd64":
-		architecture = "x86_64"
-	case "arm64":
-		architecture = "aarch_64"
-	default:
-		return fmt.Errorf("unsupported architecture: %s", runtime.GOARCH)
+	return HostPlatform().InstallProtoBufGithub(version)
+}
+
+// InstallProtoBufGithub устанавливает protoc из релиза GitHub в /usr/local на Linux и macOS.
+// Новая версия сначала распаковывается и проверяется во временном каталоге, затем
+// подменяет файлы в /usr/local. При ошибке на любом шаге предыдущая установка восстанавливается.
+func (p *Platform) InstallProtoBufGithub(version string) error {
+	return p.installProtoBuf(version, "github", func(staging string) (string, error) {
+		return p.stageProtocArchive(version, staging)
+	})
+}
+
+// InstallProtoBufArchive устанавливает protoc в /usr/local из локального архива без обращения к сети.
+// Распаковка, проверка и откат выполняются так же, как в InstallProtoBufGithub.
+func (p *Platform) InstallProtoBufArchive(archive, version string) error {
+	return p.installProtoBuf(version, "archive:"+archive, func(staging string) (string, error) {
+		return p.stageProtocTree(archive, version, staging)
+	})
+}
+
+// installProtoBuf устанавливает подготовленное функцией stage дерево protoc в /usr/local
+// и записывает установку в историю с указанием источника source.
+func (p *Platform) installProtoBuf(version, source string, stage func(staging string) (string, error)) error {
+	staging, err := os.MkdirTemp("", "protoc-"+version+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	tree, err := stage(staging)
+	if err != nil {
+		return err
+	}
+
+	prefix := p.Path("/usr/local")
+	var oldVersion string
+	if output, err := p.Runner.Output(filepath.Join(prefix, "bin", "protoc"), "--version"); err == nil {
+		oldVersion = ProtocReleaseVersion(parseProtocVersion(output))
 	}
 
-	_, err := RunCommandWithOutput("curl", "-L", "-o", "protoc.zip", fmt.Sprintf(url, version, version, architecture))
+	files, err := treeFiles(tree, prefix)
 	if err != nil {
-		return fmt.Errorf("failed to download protoc: %w", err)
+		return err
+	}
+	// Манифест хранит пути относительно корня платформы, Verify разрешает их через Path
+	manifest, err := buildManifest(HistoryTargetSystem, version, tree, "/usr/local", protocManagedPaths)
+	if err != nil {
+		return err
+	}
+
+	if err := p.swapProtocTree(tree, prefix); err != nil {
+		return err
 	}
-	defer os.Remove("protoc.zip")
 
-	if err := RunCommand("sudo", "unzip", "-o", "protoc.zip", "-d", "/usr/local"); err != nil {
-		return fmt.Errorf("failed to unzip protoc: %w", err)
+	if err := WriteManifest(manifest); err != nil {
+		log.Printf("Failed to record install manifest: %v", err)
 	}
 
-	if err := RunCommand("sudo", "chmod", "+x", "/usr/local/bin/protoc"); err != nil {
-		return fmt.Errorf("failed to chmod protoc: %w", err)
+	if err := AppendHistory(HistoryEntry{
+		Tool:       "protoc",
+		OldVersion: oldVersion,
+		NewVersion: version,
+		Source:     source,
+		Target:     HistoryTargetSystem,
+		Files:      files,
+	}); err != nil {
+		log.Printf("Failed to record installation history: %v", err)
 	}
 
 	return nil
 }
 
-func DetectLinuxDistribution() (string, string, error) {
-	file, err := os.Open("/etc/os-release")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to open /etc/os-release: %w", err)
-	}
-	defer file.Close()
-
-	var id, versionID, idLike string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "ID=") {
-			id = strings.Trim(strings.TrimPrefix(line, "ID="), `"`)
-		} else if strings.HasPrefix(line, "VERSION_ID=") {
-			versionID = strings.Trim(strings.TrimPrefix(line, "VERSION_ID="), `"`)
-		} else if strings.HasPrefix(line, "ID_LIKE=") {
-			idLike = strings.Trim(strings.TrimPrefix(line, "ID_LIKE="), `"`)
+// treeFiles возвращает пути файлов управляемой части дерева protoc после переноса в prefix.
+func treeFiles(tree, prefix string) ([]string, error) {
+	var files []string
+	for _, managed := range protocManagedPaths {
+		err := filepath.WalkDir(filepath.Join(tree, managed), func(path string, d os.DirEntry, err error) error {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(tree, path)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			files = append(files, filepath.Join(prefix, rel))
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list staged files: %w", err)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return "", "", fmt.Errorf("error reading /etc/os-release: %w", err)
+
+	return files, nil
+}
+
+// swapProtocTree переносит проверенное дерево protoc в prefix.
+// Дерево сначала копируется в служебный каталог внутри prefix, чтобы все переименования
+// выполнялись в пределах одной файловой системы и были атомарными.
+// Отмена контекста не прерывает ни перенос, ни откат: все команды, включая отложенные восстановление
+// и очистку, выполняются отвязанным раннером, иначе оборванная подмена оставила бы prefix без protoc.
+func (p *Platform) swapProtocTree(tree, prefix string) (err error) {
+	swap := *p
+	swap.Runner = detachedRunner(p.Runner)
+	p = &swap
+
+	swapDir := filepath.Join(prefix, fmt.Sprintf(".protocinstall-%d", os.Getpid()))
+	newDir := filepath.Join(swapDir, "new")
+	oldDir := filepath.Join(swapDir, "old")
+
+	writeDirs := []string{prefix}
+	for _, path := range protocManagedPaths {
+		writeDirs = append(writeDirs, filepath.Join(prefix, filepath.Dir(path)))
 	}
-	if id == "" {
-		return "", "", fmt.Errorf("could not determine Linux distribution")
+	run, err := p.privilegedRunner(writeDirs...)
+	if err != nil {
+		return err
 	}
 
-	// Handle SUSE variants
-	if strings.Contains(strings.ToLower(id), "suse") || strings.Contains(strings.ToLower(idLike), "suse") {
-		return "suse", versionID, nil
+	if err := run("mkdir", "-p", newDir, oldDir); err != nil {
+		return fmt.Errorf("failed to create swap directory: %w", err)
 	}
+	defer func() {
+		if cleanupErr := run("rm", "-rf", swapDir); cleanupErr != nil {
+			log.Printf("Failed to remove swap directory %s: %v", swapDir, cleanupErr)
+		}
+	}()
 
-	return strings.ToLower(id), versionID, nil
-}
+	if err := run("cp", "-a", tree+"/.", newDir); err != nil {
+		return fmt.Errorf("failed to copy protoc into %s: %w", prefix, err)
+	}
 
-func RemovePackageManagerProtobuf(distro string) error {
-	switch distro {
-	case "ubuntu", "debian":
-		// Check if installed
-		if _, err := RunCommandWithOutput("dpkg", "-l", "protobuf-compiler"); err == nil {
-			cmd := []string{"sudo", "apt-get", "remove", "-y", "protobuf-compiler"}
-			if err := RunCommand(cmd[0], cmd[1:]...); err != nil {
-				return fmt.Errorf("failed to remove protobuf-compiler: %w", err)
+	var backedUp, installed []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		log.Printf("Installation failed, restoring previous protoc in %s", prefix)
+		for i := len(installed) - 1; i >= 0; i-- {
+			if rmErr := run("rm", "-rf", filepath.Join(prefix, installed[i])); rmErr != nil {
+				log.Printf("Failed to remove %s: %v", installed[i], rmErr)
 			}
 		}
-
-	case "centos", "fedora", "rhel":
-		// Check if installed
-		if _, err := RunCommandWithOutput("rpm", "-q", "protobuf-compiler"); err == nil {
-			cmd := []string{"sudo", "dnf", "remove", "-y", "protobuf-compiler"}
-			if err := RunCommand(cmd[0], cmd[1:]...); err != nil {
-				return fmt.Errorf("failed to remove protobuf-compiler: %w", err)
+		for i := len(backedUp) - 1; i >= 0; i-- {
+			path := backedUp[i]
+			if mvErr := run("mv", filepath.Join(oldDir, path), filepath.Join(prefix, path)); mvErr != nil {
+				log.Printf("Failed to restore %s: %v", path, mvErr)
 			}
 		}
+	}()
 
-	case "suse":
-		// Check if installed
-		if _, err := RunCommandWithOutput("rpm", "-q", "protobuf"); err == nil {
-			cmd := []string{"sudo", "zypper", "--non-interactive", "remove", "protobuf"}
-			if err := RunCommand(cmd[0], cmd[1:]...); err != nil {
-				return fmt.Errorf("failed to remove protobuf: %w", err)
-			}
+	for _, path := range protocManagedPaths {
+		target := filepath.Join(prefix, path)
+		if err := run("mkdir", "-p", filepath.Dir(target), filepath.Dir(filepath.Join(oldDir, path))); err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", target, err)
 		}
 
-	case "alpine":
-		// Check if installed
-		if _, err := RunCommandWithOutput("apk", "info", "protobuf"); err == nil {
-			cmd := []string{"sudo", "apk", "del", "protobuf"}
-			if err := RunCommand(cmd[0], cmd[1:]...); err != nil {
-				return fmt.Errorf("failed to remove protobuf: %w", err)
+		if _, statErr := os.Lstat(target); statErr == nil {
+			if err := run("mv", target, filepath.Join(oldDir, path)); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", target, err)
 			}
+			backedUp = append(backedUp, path)
 		}
 
-	default:
-		return fmt.Errorf("unsupported distribution: %s", distro)
+		if err := run("mv", filepath.Join(newDir, path), target); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", target, err)
+		}
+		installed = append(installed, path)
+	}
+
+	if _, err := p.Runner.Output(filepath.Join(prefix, "bin", "protoc"), "--version"); err != nil {
+		return fmt.Errorf("failed to verify installed protoc: %w", err)
 	}
 
 	return nil
@@ -1,7 +1,16 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBumpVersion(t *testing.T) {
@@ -79,3 +88,96 @@ func TestBumpVersionEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// mvFailingRunner выполняет команды через inner и завершает ошибкой mv с номером failAt.
+type mvFailingRunner struct {
+	inner  CommandRunner
+	failAt int
+	mvs    *int
+}
+
+func (r mvFailingRunner) Run(command string, args ...string) error {
+	if command == "mv" {
+		*r.mvs++
+		if *r.mvs == r.failAt {
+			return errors.New("mv: cannot move: input/output error")
+		}
+	}
+
+	return r.inner.Run(command, args...)
+}
+
+func (r mvFailingRunner) RunEnv(env []string, command string, args ...string) error {
+	return r.inner.RunEnv(env, command, args...)
+}
+
+func (r mvFailingRunner) Output(command string, args ...string) ([]byte, error) {
+	return r.inner.Output(command, args...)
+}
+
+func (r mvFailingRunner) Detached() CommandRunner {
+	r.inner = detachedRunner(r.inner)
+	return r
+}
+
+// writeSwapTree создаёт дерево protoc, bin/protoc которого печатает version, а include содержит proto.
+func writeSwapTree(t *testing.T, dir, version, proto string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "include", "google", "protobuf"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "protoc"),
+		[]byte("#!/bin/sh\necho libprotoc "+version+"\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "include", "google", "protobuf", proto), nil, 0o644))
+}
+
+func TestSwapProtocTreeRollback(t *testing.T) {
+	// Контекст уже отменён: откат должен выполняться отвязанным раннером
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for failAt := 1; failAt <= 4; failAt++ {
+		t.Run(fmt.Sprintf("mv %d", failAt), func(t *testing.T) {
+			prefix, tree := t.TempDir(), t.TempDir()
+			writeSwapTree(t, prefix, "3.21.12", "old.proto")
+			writeSwapTree(t, tree, "29.3", "new.proto")
+
+			var mvs int
+			runner := mvFailingRunner{inner: ExecRunner{Context: ctx}, failAt: failAt, mvs: &mvs}
+			p := &Platform{OS: "linux", Arch: "amd64", Runner: runner, Escalation: EscalationNone}
+
+			err := p.swapProtocTree(tree, prefix)
+			require.ErrorContains(t, err, "input/output error")
+
+			protoc, err := os.ReadFile(filepath.Join(prefix, "bin", "protoc"))
+			require.NoError(t, err)
+			assert.Contains(t, string(protoc), "3.21.12", "Прежний protoc должен быть восстановлен")
+			assert.FileExists(t, filepath.Join(prefix, "include", "google", "protobuf", "old.proto"))
+			assert.NoFileExists(t, filepath.Join(prefix, "include", "google", "protobuf", "new.proto"))
+
+			entries, err := os.ReadDir(prefix)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.False(t, strings.HasPrefix(entry.Name(), ".protocinstall-"),
+					"Служебный каталог %s должен удаляться", entry.Name())
+			}
+		})
+	}
+}
+
+func TestSwapProtocTree(t *testing.T) {
+	prefix, tree := t.TempDir(), t.TempDir()
+	writeSwapTree(t, prefix, "3.21.12", "old.proto")
+	writeSwapTree(t, tree, "29.3", "new.proto")
+
+	p := &Platform{OS: "linux", Arch: "amd64", Runner: ExecRunner{}, Escalation: EscalationNone}
+	require.NoError(t, p.swapProtocTree(tree, prefix))
+
+	protoc, err := os.ReadFile(filepath.Join(prefix, "bin", "protoc"))
+	require.NoError(t, err)
+	assert.Contains(t, string(protoc), "29.3")
+	assert.FileExists(t, filepath.Join(prefix, "include", "google", "protobuf", "new.proto"))
+	assert.NoFileExists(t, filepath.Join(prefix, "include", "google", "protobuf", "old.proto"))
+	matches, err := filepath.Glob(filepath.Join(prefix, ".protocinstall-*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd(platform *utils.Platform) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check installed protoc files against the install manifest and PATH",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := platform.Verify()
+			if err != nil {
+				return fmt.Errorf("failed to verify installation: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			for _, manifest := range report.Manifests {
+				fmt.Fprintf(out, "Checked %d files of %s %s (%s) in %s\n",
+					len(manifest.Files), manifest.Tool, manifest.Version, manifest.Target, manifest.Root)
+			}
+			for _, path := range report.Modified {
+				fmt.Fprintf(out, "MODIFIED %s\n", path)
+			}
+			for _, path := range report.Missing {
+				fmt.Fprintf(out, "MISSING  %s\n", path)
+			}
+			for _, path := range report.WrongVersion {
+				fmt.Fprintf(out, "VERSION  %s\n", path)
+			}
+			for _, path := range report.Foreign {
+				fmt.Fprintf(out, "FOREIGN  %s\n", path)
+			}
+			switch {
+			case report.PathProtoc == "":
+				fmt.Fprintln(out, "protoc is not on PATH")
+			case report.Shadowed:
+				fmt.Fprintf(out, "protoc on PATH is %s, which is not a managed install\n", report.PathProtoc)
+			default:
+				fmt.Fprintf(out, "protoc on PATH is the managed %s\n", report.PathProtoc)
+			}
+
+			if !report.OK() {
+				return fmt.Errorf("%w: %d modified, %d missing, %d wrong version, shadowed: %t", utils.ErrVerificationFailed,
+					len(report.Modified), len(report.Missing), len(report.WrongVersion), report.Shadowed)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/robertt3kuk/protocInstall/utils"
+	"github.com/spf13/cobra"
+)
+
+func newInstallCmd(platform *utils.Platform) *cobra.Command {
+	var (
+		use  bool
+		from string
+	)
+	cmd := &cobra.Command{
+		Use:   "install [version]",
+		Short: "Install a protoc version side by side into the versions directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withInstallLock(cmd, func() error {
+				return installProtocVersion(platform, args, from, use)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&use, "use", false, "make the installed version active")
+	cmd.Flags().StringVar(&from, "from", "", "install offline from a protoc release zip or a mirror directory")
+
+	return cmd
+}
+
+// installProtocVersion устанавливает версию из args (по умолчанию последний релиз GitHub) в каталог версий
+// и делает её активной, если use задан или активной версии ещё нет.
+func installProtocVersion(platform *utils.Platform, args []string, from string, use bool) error {
+	var version string
+	if len(args) > 0 {
+		version = protocPin(args[0])
+	}
+
+	switch {
+	case from != "":
+		archive, archiveVersion, err := platform.ResolveLocalProtocArchive(from, version)
+		if err != nil {
+			return fmt.Errorf("failed to resolve local protoc archive: %w", err)
+		}
+		version = archiveVersion
+		if _, err := platform.InstallProtocVersionFromArchive(archive, version); err != nil {
+			return fmt.Errorf("failed to install protoc %s from %s: %w", version, archive, err)
+		}
+	default:
+		if version == "" {
+			// Каталог версий заполняется релизами GitHub, поэтому версия Homebrew на darwin здесь не подходит
+			log.Printf("Fetching latest protoc release")
+			latestVersion, err := platform.GetGithubProtocVersion()
+			if err != nil {
+				return fmt.Errorf("failed to get latest protoc release: %w", err)
+			}
+			version = latestVersion
+		}
+		if _, err := platform.InstallProtocVersion(version); err != nil {
+			return fmt.Errorf("failed to install protoc %s: %w", version, err)
+		}
+	}
+
+	active, err := utils.ActiveProtocVersion()
+	if err != nil {
+		return err
+	}
+	if !use && active != "" {
+		return nil
+	}
+
+	return switchProtocVersion(version)
+}
+
+func newUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <version>",
+		Short: "Switch the active protoc to an installed version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withInstallLock(cmd, func() error {
+				return switchProtocVersion(protocPin(args[0]))
+			})
+		},
+	}
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List installed protoc versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			versions, err := utils.ListInstalledProtocVersions()
+			if err != nil {
+				return err
+			}
+			active, err := utils.ActiveProtocVersion()
+			if err != nil {
+				return err
+			}
+
+			for _, version := range versions {
+				marker := " "
+				if version == active {
+					marker = "*"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", marker, version)
+			}
+
+			return nil
+		},
+	}
+}
+
+func switchProtocVersion(version string) error {
+	if err := utils.UseProtocVersion(version); err != nil {
+		return fmt.Errorf("failed to switch protoc version: %w", err)
+	}
+	log.Printf("Active protoc version: %s", version)
+
+	binDir, err := utils.ProtocCurrentBinDir()
+	if err != nil {
+		return err
+	}
+	warnIfNotOnPath(binDir)
+
+	return nil
+}
+
+// warnIfNotOnPath подсказывает пользователю добавить dir в PATH, если его там нет,
+// и предупреждает, если раньше dir в PATH находится другой protoc.
+func warnIfNotOnPath(dir string) {
+	for _, pathDir := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(pathDir) == filepath.Clean(dir) {
+			if other := utils.ShadowingBinary(filepath.Join(dir, "protoc")); other != "" {
+				log.Printf("Warning: %s comes first on PATH and shadows the installed protoc in %s; run: export PATH=%s:$PATH",
+					other, dir, dir)
+			}
+			return
+		}
+	}
+	log.Printf("Add %s to your PATH to use the installed protoc", dir)
+}